@@ -0,0 +1,137 @@
+package bundle
+
+import (
+	"tssh/database"
+	"tssh/models"
+)
+
+// Version 导出文件的格式版本
+const Version = 1
+
+// Bundle 导出文件的内容, 其中的密码和私钥均为明文
+// 连接通过 IdentityID 引用同一文件中的身份, 导入时按身份名称重新关联
+type Bundle struct {
+	Version int `json:"version"`
+	// Secrets 为 false 表示导出时未包含密码、私钥内容和 TOTP 密钥
+	Secrets     bool              `json:"secrets"`
+	Connections []models.ConnInfo `json:"connections"`
+	Identities  []models.Identity `json:"identities,omitempty"`
+	Keys        []models.SSHKey   `json:"keys,omitempty"`
+}
+
+// Export 导出存储中的全部个人连接, 以及存储支持时的身份和密钥
+// withSecrets 为 false 时不导出任何密码类字段
+func Export(store database.Store, withSecrets bool) (*Bundle, error) {
+	b := &Bundle{Version: Version, Secrets: withSecrets}
+
+	connections, err := store.GetAllConnections()
+	if err != nil {
+		return nil, err
+	}
+	for _, conn := range connections {
+		// 共享清单中的连接由清单文件自身分发
+		if conn.Source != "" {
+			continue
+		}
+		if conn.IdentityID != 0 {
+			// 列表中的用户名来自身份, 导出连接自身保存的值没有意义
+			conn.Username = ""
+		}
+		if err := exportSecrets(withSecrets, connSecrets(&conn)...); err != nil {
+			return nil, err
+		}
+		b.Connections = append(b.Connections, conn)
+	}
+
+	if identities, ok := database.As[database.IdentityStore](store); ok {
+		all, err := identities.GetAllIdentities()
+		if err != nil {
+			return nil, err
+		}
+		for _, identity := range all {
+			if err := exportSecrets(withSecrets, identitySecrets(&identity)...); err != nil {
+				return nil, err
+			}
+			b.Identities = append(b.Identities, identity)
+		}
+	}
+
+	if keys, ok := database.As[database.KeyStore](store); ok {
+		all, err := keys.GetAllKeys()
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range all {
+			if err := exportSecrets(withSecrets, &key.PrivateKey); err != nil {
+				return nil, err
+			}
+			// 不含私钥的密钥无法导入, 只导出公钥也没有意义
+			if key.PrivateKey == "" {
+				continue
+			}
+			b.Keys = append(b.Keys, key)
+		}
+	}
+	return b, nil
+}
+
+// Select 只保留匹配关键字的连接及其引用的身份, 用于分享部分连接
+// 选择部分连接时不再包含密钥
+func (b *Bundle) Select(keyword string) {
+	used := make(map[int64]bool)
+	var connections []models.ConnInfo
+	for _, conn := range b.Connections {
+		if !conn.Matches(keyword) {
+			continue
+		}
+		connections = append(connections, conn)
+		used[conn.IdentityID] = true
+		for _, id := range conn.ExtraIdentityIDs {
+			used[id] = true
+		}
+	}
+	var identities []models.Identity
+	for _, identity := range b.Identities {
+		if used[identity.ID] {
+			identities = append(identities, identity)
+		}
+	}
+	b.Connections = connections
+	b.Identities = identities
+	b.Keys = nil
+}
+
+// connSecrets 返回连接中加密存储的字段
+func connSecrets(conn *models.ConnInfo) []*string {
+	return []*string{&conn.Password, &conn.KeyData, &conn.KeyPassphrase, &conn.TOTPSecret}
+}
+
+// identitySecrets 返回身份中加密存储的字段
+func identitySecrets(identity *models.Identity) []*string {
+	return []*string{&identity.Password, &identity.KeyData, &identity.KeyPassphrase}
+}
+
+// exportSecrets 解密字段, withSecrets 为 false 时清空字段
+func exportSecrets(withSecrets bool, fields ...*string) error {
+	if !withSecrets {
+		for _, f := range fields {
+			*f = ""
+		}
+		return nil
+	}
+	return decryptFields(fields...)
+}
+
+func decryptFields(fields ...*string) error {
+	for _, f := range fields {
+		if *f == "" {
+			continue
+		}
+		plain, err := models.DecryptString(*f)
+		if err != nil {
+			return err
+		}
+		*f = plain
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package bundle
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"tssh/models"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// 导出文件的格式标识
+const (
+	formatPlain     = "tssh-bundle"
+	formatEncrypted = "tssh-bundle-encrypted"
+)
+
+// scrypt 参数, 写入文件头以便日后调整
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+	keyLen  = 32
+)
+
+// 解密时允许的 scrypt 参数上限, 避免恶意文件耗尽内存和 CPU
+const (
+	maxScryptN = 1 << 20
+	maxScryptR = 32
+	maxScryptP = 16
+)
+
+var ErrPassphrase = errors.New("wrong passphrase or corrupted bundle")
+
+type plainFile struct {
+	Format string `json:"format"`
+	*Bundle
+}
+
+// encryptedFile 加密的导出文件, 内容为 AES-256-GCM 加密的 plainFile
+// 密钥由口令经 scrypt 派生
+type encryptedFile struct {
+	Format     string `json:"format"`
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Encode 序列化导出内容, passphrase 为空时不加密
+func Encode(b *Bundle, passphrase []byte) ([]byte, error) {
+	data, err := json.MarshalIndent(plainFile{Format: formatPlain, Bundle: b}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if len(passphrase) == 0 {
+		return data, nil
+	}
+	defer models.Zero(data)
+
+	f := encryptedFile{
+		Format:  formatEncrypted,
+		Version: Version,
+		KDF:     "scrypt",
+		N:       scryptN,
+		R:       scryptR,
+		P:       scryptP,
+		Salt:    make([]byte, 16),
+	}
+	if _, err := rand.Read(f.Salt); err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(passphrase, f)
+	if err != nil {
+		return nil, err
+	}
+	f.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(f.Nonce); err != nil {
+		return nil, err
+	}
+	f.Ciphertext = gcm.Seal(nil, f.Nonce, data, []byte(formatEncrypted))
+	return json.MarshalIndent(f, "", "  ")
+}
+
+// Protection 导出文件的加密方式
+type Protection int
+
+const (
+	// Unencrypted 未加密, 不包含密码类字段
+	Unencrypted Protection = iota
+	// Passphrase 使用口令加密
+	Passphrase
+	// Recipients 使用接收者的公钥加密
+	Recipients
+)
+
+// Detect 判断导出文件的加密方式
+func Detect(data []byte) (Protection, error) {
+	if isAgeFile(data) {
+		return Recipients, nil
+	}
+	var header struct {
+		Format string `json:"format"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return 0, fmt.Errorf("invalid bundle: %w", err)
+	}
+	switch header.Format {
+	case formatPlain:
+		return Unencrypted, nil
+	case formatEncrypted:
+		return Passphrase, nil
+	}
+	return 0, fmt.Errorf("unknown bundle format: %q", header.Format)
+}
+
+// Decode 解析未加密或使用口令加密的导出文件, 加密的文件使用 passphrase 解密
+// 加密给接收者的文件使用 DecodeWith 解析
+func Decode(data, passphrase []byte) (*Bundle, error) {
+	protection, err := Detect(data)
+	if err != nil {
+		return nil, err
+	}
+	switch protection {
+	case Recipients:
+		return nil, errors.New("bundle is encrypted to recipients, an identity is required")
+	case Passphrase:
+		var f encryptedFile
+		if err := json.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("invalid bundle: %w", err)
+		}
+		if f.KDF != "scrypt" {
+			return nil, fmt.Errorf("unsupported key derivation: %s", f.KDF)
+		}
+		gcm, err := newGCM(passphrase, f)
+		if err != nil {
+			return nil, err
+		}
+		if len(f.Nonce) != gcm.NonceSize() {
+			return nil, errors.New("invalid bundle: bad nonce size")
+		}
+		data, err = gcm.Open(nil, f.Nonce, f.Ciphertext, []byte(formatEncrypted))
+		if err != nil {
+			return nil, ErrPassphrase
+		}
+		defer models.Zero(data)
+	}
+
+	f := plainFile{Bundle: &Bundle{}}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+	if f.Format != formatPlain {
+		return nil, fmt.Errorf("unknown bundle format: %q", f.Format)
+	}
+	if f.Bundle.Version > Version {
+		return nil, fmt.Errorf("bundle version %d is newer than supported version %d", f.Bundle.Version, Version)
+	}
+	return f.Bundle, nil
+}
+
+// checkScrypt 校验文件头中的 scrypt 参数, 参数来自不可信的文件
+func checkScrypt(f encryptedFile) error {
+	if f.N < 2 || f.N > maxScryptN || f.N&(f.N-1) != 0 {
+		return fmt.Errorf("invalid bundle: scrypt N must be a power of two up to %d", maxScryptN)
+	}
+	if f.R < 1 || f.R > maxScryptR {
+		return fmt.Errorf("invalid bundle: scrypt r must be between 1 and %d", maxScryptR)
+	}
+	if f.P < 1 || f.P > maxScryptP {
+		return fmt.Errorf("invalid bundle: scrypt p must be between 1 and %d", maxScryptP)
+	}
+	return nil
+}
+
+func newGCM(passphrase []byte, f encryptedFile) (cipher.AEAD, error) {
+	if err := checkScrypt(f); err != nil {
+		return nil, err
+	}
+	key, err := scrypt.Key(passphrase, f.Salt, f.N, f.R, f.P, keyLen)
+	if err != nil {
+		return nil, err
+	}
+	defer models.Zero(key)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,115 @@
+package bundle
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"tssh/database"
+	"tssh/models"
+)
+
+func testBundle(t *testing.T) *Bundle {
+	t.Helper()
+	store := database.NewMemoryStore()
+	conn := models.ConnInfo{
+		Name:     "web",
+		Host:     "web.example.com",
+		Port:     22,
+		Username: "root",
+		AuthType: models.UsePass,
+		Password: "web-pass",
+	}
+	if err := store.AddConnection(conn); err != nil {
+		t.Fatal(err)
+	}
+	b, err := Export(store, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	for _, passphrase := range []string{"", "correct horse"} {
+		data, err := Encode(testBundle(t), []byte(passphrase))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if passphrase != "" && strings.Contains(string(data), "web-pass") {
+			t.Fatal("encrypted bundle contains the plaintext password")
+		}
+		want := Unencrypted
+		if passphrase != "" {
+			want = Passphrase
+		}
+		if protection, err := Detect(data); err != nil || protection != want {
+			t.Fatalf("Detect = %v, %v, want %v", protection, err, want)
+		}
+
+		b, err := Decode(data, []byte(passphrase))
+		if err != nil {
+			t.Fatal(err)
+		}
+		store := database.NewMemoryStore()
+		if _, err := Import(store, b, StrategyMerge, false); err != nil {
+			t.Fatal(err)
+		}
+		connections, err := store.GetAllConnections()
+		if err != nil || len(connections) != 1 {
+			t.Fatalf("imported %d connections, err %v", len(connections), err)
+		}
+		pass, err := models.DecryptString(connections[0].Password)
+		if err != nil || pass != "web-pass" || connections[0].Host != "web.example.com" {
+			t.Errorf("imported connection %+v, password %q, err %v", connections[0], pass, err)
+		}
+	}
+}
+
+func TestDecodeWrongPassphrase(t *testing.T) {
+	data, err := Encode(testBundle(t), []byte("correct horse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Decode(data, []byte("wrong")); !errors.Is(err, ErrPassphrase) {
+		t.Errorf("Decode with wrong passphrase returned %v, want ErrPassphrase", err)
+	}
+}
+
+func TestDecodeTampered(t *testing.T) {
+	data, err := Encode(testBundle(t), []byte("correct horse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		tamper func(f *encryptedFile)
+	}{
+		{"ciphertext", func(f *encryptedFile) { f.Ciphertext[0] ^= 1 }},
+		{"nonce", func(f *encryptedFile) { f.Nonce[0] ^= 1 }},
+		{"short nonce", func(f *encryptedFile) { f.Nonce = f.Nonce[:4] }},
+		{"salt", func(f *encryptedFile) { f.Salt[0] ^= 1 }},
+		{"huge N", func(f *encryptedFile) { f.N = 1 << 30 }},
+		{"N not a power of two", func(f *encryptedFile) { f.N = 3 << 10 }},
+		{"huge r", func(f *encryptedFile) { f.R = 1 << 10 }},
+		{"huge p", func(f *encryptedFile) { f.P = 1 << 10 }},
+		{"zero p", func(f *encryptedFile) { f.P = 0 }},
+		{"kdf", func(f *encryptedFile) { f.KDF = "none" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var f encryptedFile
+			if err := json.Unmarshal(data, &f); err != nil {
+				t.Fatal(err)
+			}
+			tt.tamper(&f)
+			tampered, err := json.Marshal(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Decode(tampered, []byte("correct horse")); err == nil {
+				t.Error("Decode accepted a tampered bundle")
+			}
+		})
+	}
+}
@@ -0,0 +1,437 @@
+package bundle
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"tssh/database"
+	"tssh/models"
+)
+
+// Strategy 导入时处理同名条目的方式
+type Strategy string
+
+const (
+	// StrategyMerge 用导出文件中非空的字段更新已有条目
+	StrategyMerge Strategy = "merge"
+	// StrategyOverwrite 用导出文件中的条目整体替换已有条目, 导出文件中为空的密码保留原值
+	StrategyOverwrite Strategy = "overwrite"
+	// StrategySkip 保留已有条目不变
+	StrategySkip Strategy = "skip"
+)
+
+// Strategies 支持的导入方式
+var Strategies = []Strategy{StrategyMerge, StrategyOverwrite, StrategySkip}
+
+// 导入时对条目执行的操作
+const (
+	ActionAdd       = "add"
+	ActionUpdate    = "update"
+	ActionSkip      = "skip"
+	ActionUnchanged = "unchanged"
+)
+
+// Change 导入一个条目的结果, 用于生成导入报告
+type Change struct {
+	Kind   string // connection, identity 或 key
+	Name   string
+	Action string
+	// Fields 发生变化的字段, 密码类字段只列出名称
+	Fields []string
+	Err    error
+}
+
+func (c Change) String() string {
+	s := fmt.Sprintf("%-9s %-10s %s", c.Action, c.Kind, c.Name)
+	if len(c.Fields) > 0 {
+		s += " (" + strings.Join(c.Fields, ", ") + ")"
+	}
+	if c.Err != nil {
+		s += ": " + c.Err.Error()
+	}
+	return s
+}
+
+// Import 将导出内容导入存储, 按名称匹配已有的连接和身份, 按指纹匹配密钥
+// dryRun 为 true 时只生成报告不修改存储; 单个条目失败不影响其余条目, 错误记录在 Change 中
+func Import(store database.Store, b *Bundle, strategy Strategy, dryRun bool) ([]Change, error) {
+	if !slices.Contains(Strategies, strategy) {
+		return nil, fmt.Errorf("unknown import strategy: %s", strategy)
+	}
+	im := &importer{store: store, bundle: b, strategy: strategy, dryRun: dryRun}
+	steps := []func() error{im.importKeys, im.importIdentities, im.importConnections}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return im.changes, err
+		}
+	}
+	return im.changes, nil
+}
+
+type importer struct {
+	store    database.Store
+	bundle   *Bundle
+	strategy Strategy
+	dryRun   bool
+	changes  []Change
+	// identityNames 导出文件中身份 ID 到名称的映射
+	identityNames map[int64]string
+	// identityIDs 存储中身份名称到 ID 的映射
+	identityIDs map[string]int64
+}
+
+func (im *importer) record(c Change) {
+	im.changes = append(im.changes, c)
+}
+
+func (im *importer) importKeys() error {
+	if len(im.bundle.Keys) == 0 {
+		return nil
+	}
+	store, ok := database.As[database.KeyStore](im.store)
+	if !ok {
+		for _, key := range im.bundle.Keys {
+			im.record(Change{Kind: "key", Name: key.Name, Action: ActionSkip, Err: fmt.Errorf("store does not support keys")})
+		}
+		return nil
+	}
+	existing, err := store.GetAllKeys()
+	if err != nil {
+		return err
+	}
+	for _, key := range im.bundle.Keys {
+		// 密钥内容不可修改, 指纹相同即视为同一密钥
+		if slices.ContainsFunc(existing, func(k models.SSHKey) bool { return k.Fingerprint == key.Fingerprint }) {
+			im.record(Change{Kind: "key", Name: key.Name, Action: ActionUnchanged})
+			continue
+		}
+		c := Change{Kind: "key", Name: key.Name, Action: ActionAdd}
+		if !im.dryRun {
+			c.Err = store.AddKey(key)
+		}
+		im.record(c)
+	}
+	return nil
+}
+
+func (im *importer) importIdentities() error {
+	im.identityNames = make(map[int64]string)
+	for _, identity := range im.bundle.Identities {
+		im.identityNames[identity.ID] = identity.Name
+	}
+	store, ok := database.As[database.IdentityStore](im.store)
+	if !ok {
+		// 连接导入时直接使用身份的凭据
+		return nil
+	}
+	existing, err := store.GetAllIdentities()
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]models.Identity)
+	for _, identity := range existing {
+		byName[identity.Name] = identity
+	}
+
+	for _, identity := range im.bundle.Identities {
+		c := Change{Kind: "identity", Name: identity.Name}
+		old, found := byName[identity.Name]
+		if !found {
+			c.Action = ActionAdd
+			if !im.dryRun {
+				c.Err = store.AddIdentity(identity)
+			}
+			im.record(c)
+			continue
+		}
+		if err := decryptFields(identitySecrets(&old)...); err != nil {
+			c.Action, c.Err = ActionSkip, err
+			im.record(c)
+			continue
+		}
+		updated := identity
+		if im.strategy == StrategyMerge {
+			updated = mergeIdentity(old, identity)
+		}
+		updated.ID = old.ID
+		if im.strategy == StrategyOverwrite && !im.bundle.Secrets {
+			// 未导出私钥内容时保留已导入的私钥
+			updated.KeyData = old.KeyData
+		}
+		c.Fields = identityDiff(old, updated)
+		c.Action = im.action(c.Fields)
+		if c.Action == ActionUpdate && !im.dryRun {
+			c.Err = store.UpdateIdentity(updated)
+		}
+		im.record(c)
+	}
+
+	if !im.dryRun {
+		if existing, err = store.GetAllIdentities(); err != nil {
+			return err
+		}
+	}
+	im.identityIDs = make(map[string]int64)
+	for _, identity := range existing {
+		if _, ok := im.identityIDs[identity.Name]; !ok {
+			im.identityIDs[identity.Name] = identity.ID
+		}
+	}
+	// 预演时尚未添加的身份使用负数占位, 避免与已有身份的 ID 冲突
+	for i, identity := range im.bundle.Identities {
+		if _, ok := im.identityIDs[identity.Name]; !ok && im.dryRun {
+			im.identityIDs[identity.Name] = int64(-i - 1)
+		}
+	}
+	return nil
+}
+
+func (im *importer) importConnections() error {
+	existing, err := im.store.GetAllConnections()
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]models.ConnInfo)
+	for _, conn := range existing {
+		if conn.Source == "" {
+			byName[conn.Name] = conn
+		}
+	}
+
+	for _, conn := range im.bundle.Connections {
+		c := Change{Kind: "connection", Name: conn.Name}
+		if err := im.relinkIdentities(&conn); err != nil {
+			c.Action, c.Err = ActionSkip, err
+			im.record(c)
+			continue
+		}
+		old, found := byName[conn.Name]
+		if !found {
+			c.Action = ActionAdd
+			if !im.dryRun {
+				c.Err = im.store.AddConnection(conn)
+			}
+			im.record(c)
+			continue
+		}
+		if err := decryptFields(connSecrets(&old)...); err != nil {
+			c.Action, c.Err = ActionSkip, err
+			im.record(c)
+			continue
+		}
+		updated := conn
+		if im.strategy == StrategyMerge {
+			updated = mergeConn(old, conn)
+		}
+		updated.ID = old.ID
+		updated.Version = old.Version
+		if im.strategy == StrategyOverwrite && !im.bundle.Secrets {
+			updated.KeyData = old.KeyData
+		}
+		c.Fields = im.connDiff(old, updated)
+		c.Action = im.action(c.Fields)
+		if c.Action == ActionUpdate && !im.dryRun {
+			c.Err = im.store.UpdateConnection(updated)
+		}
+		im.record(c)
+	}
+	return nil
+}
+
+// action 根据导入方式和字段差异决定对已有条目的操作
+func (im *importer) action(fields []string) string {
+	switch {
+	case len(fields) == 0:
+		return ActionUnchanged
+	case im.strategy == StrategySkip:
+		return ActionSkip
+	}
+	return ActionUpdate
+}
+
+// relinkIdentities 将连接引用的身份 ID 替换为存储中同名身份的 ID
+// 存储不支持身份时直接使用身份的凭据
+func (im *importer) relinkIdentities(conn *models.ConnInfo) error {
+	if im.identityIDs == nil {
+		conn.ExtraIdentityIDs = nil
+		if conn.IdentityID == 0 {
+			return nil
+		}
+		for _, identity := range im.bundle.Identities {
+			if identity.ID == conn.IdentityID {
+				conn.ApplyIdentity(identity)
+				conn.IdentityID = 0
+				return nil
+			}
+		}
+		return fmt.Errorf("identity %d not found in bundle", conn.IdentityID)
+	}
+
+	if conn.IdentityID != 0 {
+		id, err := im.lookupIdentity(conn.IdentityID)
+		if err != nil {
+			return err
+		}
+		conn.IdentityID = id
+	}
+	extras := conn.ExtraIdentityIDs
+	conn.ExtraIdentityIDs = nil
+	for _, extra := range extras {
+		id, err := im.lookupIdentity(extra)
+		if err != nil {
+			return err
+		}
+		conn.ExtraIdentityIDs = append(conn.ExtraIdentityIDs, id)
+	}
+	return nil
+}
+
+func (im *importer) lookupIdentity(bundleID int64) (int64, error) {
+	name, ok := im.identityNames[bundleID]
+	if !ok {
+		return 0, fmt.Errorf("identity %d not found in bundle", bundleID)
+	}
+	id, ok := im.identityIDs[name]
+	if !ok {
+		return 0, fmt.Errorf("identity %s was not imported", name)
+	}
+	return id, nil
+}
+
+// mergeConn 用 conn 中非空的字段覆盖 old
+func mergeConn(old, conn models.ConnInfo) models.ConnInfo {
+	merged := old
+	mergeString(&merged.Host, conn.Host)
+	if conn.Port != 0 {
+		merged.Port = conn.Port
+	}
+	mergeString(&merged.Username, conn.Username)
+	if conn.AuthType != 0 {
+		merged.AuthType = conn.AuthType
+	}
+	mergeString(&merged.Password, conn.Password)
+	mergeString(&merged.PrivateKey, conn.PrivateKey)
+	mergeString(&merged.KeyData, conn.KeyData)
+	mergeString(&merged.KeyPassphrase, conn.KeyPassphrase)
+	if conn.IdentityID != 0 {
+		merged.IdentityID = conn.IdentityID
+	}
+	for _, id := range conn.ExtraIdentityIDs {
+		if !slices.Contains(merged.ExtraIdentityIDs, id) {
+			merged.ExtraIdentityIDs = append(merged.ExtraIdentityIDs, id)
+		}
+	}
+	mergeString(&merged.TOTPSecret, conn.TOTPSecret)
+	mergeString(&merged.SecretBackend, conn.SecretBackend)
+	mergeString(&merged.SecretRef, conn.SecretRef)
+	mergeString(&merged.Notes, conn.Notes)
+	if len(conn.Meta) > 0 {
+		merged.Meta = maps.Clone(old.Meta)
+		if merged.Meta == nil {
+			merged.Meta = make(map[string]string)
+		}
+		maps.Copy(merged.Meta, conn.Meta)
+	}
+	return merged
+}
+
+// mergeIdentity 用 identity 中非空的字段覆盖 old
+func mergeIdentity(old, identity models.Identity) models.Identity {
+	merged := old
+	mergeString(&merged.Username, identity.Username)
+	if identity.AuthType != 0 {
+		merged.AuthType = identity.AuthType
+	}
+	mergeString(&merged.Password, identity.Password)
+	mergeString(&merged.PrivateKey, identity.PrivateKey)
+	mergeString(&merged.KeyData, identity.KeyData)
+	mergeString(&merged.KeyPassphrase, identity.KeyPassphrase)
+	return merged
+}
+
+func mergeString(dst *string, value string) {
+	if value != "" {
+		*dst = value
+	}
+}
+
+// connDiff 列出连接中发生变化的字段
+// 新值中为空的密码类字段在更新时保留原值, 不算作变化
+func (im *importer) connDiff(old, conn models.ConnInfo) []string {
+	var d differ
+	d.field("host", old.Host, conn.Host)
+	d.field("port", old.Port, conn.Port)
+	if conn.IdentityID == 0 {
+		d.field("username", old.Username, conn.Username)
+		d.field("auth", old.AuthType, conn.AuthType)
+	}
+	d.secret("password", old.Password, conn.Password)
+	d.field("private_key", old.PrivateKey, conn.PrivateKey)
+	if old.KeyData != conn.KeyData {
+		d.fields = append(d.fields, "key_data")
+	}
+	d.secret("key_passphrase", old.KeyPassphrase, conn.KeyPassphrase)
+	d.field("identity", im.identityName(old.IdentityID), im.identityName(conn.IdentityID))
+	if !slices.Equal(sorted(old.ExtraIdentityIDs), sorted(conn.ExtraIdentityIDs)) {
+		d.fields = append(d.fields, "extra_identities")
+	}
+	d.secret("totp_secret", old.TOTPSecret, conn.TOTPSecret)
+	d.field("secret_backend", old.SecretBackend, conn.SecretBackend)
+	d.field("secret_ref", old.SecretRef, conn.SecretRef)
+	if old.Notes != conn.Notes {
+		d.fields = append(d.fields, "notes")
+	}
+	if !maps.Equal(old.Meta, conn.Meta) {
+		d.fields = append(d.fields, fmt.Sprintf("meta: %v -> %v", old.Meta, conn.Meta))
+	}
+	return d.fields
+}
+
+func identityDiff(old, identity models.Identity) []string {
+	var d differ
+	d.field("username", old.Username, identity.Username)
+	d.field("auth", old.AuthType, identity.AuthType)
+	d.secret("password", old.Password, identity.Password)
+	d.field("private_key", old.PrivateKey, identity.PrivateKey)
+	if old.KeyData != identity.KeyData {
+		d.fields = append(d.fields, "key_data")
+	}
+	d.secret("key_passphrase", old.KeyPassphrase, identity.KeyPassphrase)
+	return d.fields
+}
+
+// identityName 返回存储中身份 ID 对应的名称
+func (im *importer) identityName(id int64) string {
+	if id == 0 {
+		return ""
+	}
+	for name, identityID := range im.identityIDs {
+		if identityID == id {
+			return name
+		}
+	}
+	return fmt.Sprintf("#%d", id)
+}
+
+type differ struct {
+	fields []string
+}
+
+func (d *differ) field(name string, old, value any) {
+	if old != value {
+		d.fields = append(d.fields, fmt.Sprintf("%s: %v -> %v", name, old, value))
+	}
+}
+
+func (d *differ) secret(name, old, value string) {
+	if value != "" && old != value {
+		d.fields = append(d.fields, name)
+	}
+}
+
+func sorted(ids []int64) []int64 {
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	return ids
+}
@@ -0,0 +1,122 @@
+package bundle
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"tssh/models"
+
+	"filippo.io/age"
+	"filippo.io/age/agessh"
+	"filippo.io/age/armor"
+	"golang.org/x/crypto/ssh"
+)
+
+// ParseRecipient 解析接收者的公钥
+// 支持 age X25519 公钥 (age1...) 以及 ssh-ed25519 和 ssh-rsa 公钥
+func ParseRecipient(s string) (age.Recipient, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "age1") {
+		return age.ParseX25519Recipient(s)
+	}
+	if strings.HasPrefix(s, "ssh-") {
+		return agessh.ParseRecipient(s)
+	}
+	return nil, fmt.Errorf("unknown recipient type: %q", s)
+}
+
+// ParseRecipients 解析每行一个公钥的接收者文件, 如 .pub 或 authorized_keys 文件
+// 忽略空行和以 # 开头的注释
+func ParseRecipients(r io.Reader) ([]age.Recipient, error) {
+	var recipients []age.Recipient
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		recipient, err := ParseRecipient(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		recipients = append(recipients, recipient)
+	}
+	return recipients, scanner.Err()
+}
+
+// ParseIdentity 解析接收者的私钥, 支持 age 私钥文件和 OpenSSH 私钥
+// 私钥有密码时在解密时调用 passphrase 获取密码
+func ParseIdentity(data []byte, passphrase func() ([]byte, error)) ([]age.Identity, error) {
+	if bytes.Contains(data, []byte("AGE-SECRET-KEY-")) {
+		return age.ParseIdentities(bytes.NewReader(data))
+	}
+	identity, err := agessh.ParseIdentity(data)
+	if err == nil {
+		return []age.Identity{identity}, nil
+	}
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) && missing.PublicKey != nil {
+		identity, err := agessh.NewEncryptedSSHIdentity(missing.PublicKey, data, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		return []age.Identity{identity}, nil
+	}
+	return nil, err
+}
+
+// EncodeFor 序列化导出内容并加密给指定的接收者, 输出为 ASCII 格式的 age 文件
+func EncodeFor(b *Bundle, recipients []age.Recipient) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("no recipients")
+	}
+	data, err := Encode(b, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer models.Zero(data)
+
+	var buf bytes.Buffer
+	aw := armor.NewWriter(&buf)
+	w, err := age.Encrypt(aw, recipients...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	if err := aw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeWith 使用接收者的私钥解密并解析导出文件
+func DecodeWith(data []byte, identities []age.Identity) (*Bundle, error) {
+	var r io.Reader = bytes.NewReader(data)
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(armor.Header)) {
+		r = armor.NewReader(r)
+	}
+	dr, err := age.Decrypt(r, identities...)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := io.ReadAll(dr)
+	if err != nil {
+		return nil, err
+	}
+	defer models.Zero(plain)
+	return Decode(plain, nil)
+}
+
+// isAgeFile 判断导出文件是否为加密给接收者的 age 文件
+func isAgeFile(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	return bytes.HasPrefix(data, []byte(armor.Header)) || bytes.HasPrefix(data, []byte("age-encryption.org/"))
+}
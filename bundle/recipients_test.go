@@ -0,0 +1,145 @@
+package bundle
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"tssh/database"
+	"tssh/models"
+
+	"filippo.io/age"
+	"golang.org/x/crypto/ssh"
+)
+
+// sshKeyPair 生成 ed25519 密钥对, 返回 authorized_keys 格式的公钥和 OpenSSH 格式的私钥
+func sshKeyPair(t *testing.T, passphrase string) (string, []byte) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var block *pem.Block
+	if passphrase == "" {
+		block, err = ssh.MarshalPrivateKey(priv, "")
+	} else {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(priv, "", []byte(passphrase))
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(ssh.MarshalAuthorizedKey(sshPub)), pem.EncodeToMemory(block)
+}
+
+func checkImported(t *testing.T, b *Bundle) {
+	t.Helper()
+	store := database.NewMemoryStore()
+	if _, err := Import(store, b, StrategyMerge, false); err != nil {
+		t.Fatal(err)
+	}
+	connections, err := store.GetAllConnections()
+	if err != nil || len(connections) != 1 {
+		t.Fatalf("imported %d connections, err %v", len(connections), err)
+	}
+	if pass, err := models.DecryptString(connections[0].Password); err != nil || pass != "web-pass" {
+		t.Errorf("imported password %q, err %v", pass, err)
+	}
+}
+
+func TestRecipientsRoundTrip(t *testing.T) {
+	x25519, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshPub, sshPriv := sshKeyPair(t, "")
+	encPub, encPriv := sshKeyPair(t, "key-pass")
+
+	tests := []struct {
+		name       string
+		recipient  string
+		identity   string
+		passphrase string
+	}{
+		{"x25519", x25519.Recipient().String(), x25519.String() + "\n", ""},
+		{"ssh-ed25519", sshPub, string(sshPriv), ""},
+		{"encrypted ssh key", encPub, string(encPriv), "key-pass"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 接收者文件中可以有注释和空行
+			recipients, err := ParseRecipients(strings.NewReader("# team\n\n" + tt.recipient + "\n"))
+			if err != nil || len(recipients) != 1 {
+				t.Fatalf("ParseRecipients = %v, %v", recipients, err)
+			}
+			data, err := EncodeFor(testBundle(t), recipients)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(data), "web-pass") {
+				t.Fatal("encrypted bundle contains the plaintext password")
+			}
+			if protection, err := Detect(data); err != nil || protection != Recipients {
+				t.Fatalf("Detect = %v, %v, want Recipients", protection, err)
+			}
+
+			identities, err := ParseIdentity([]byte(tt.identity), func() ([]byte, error) {
+				return []byte(tt.passphrase), nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := DecodeWith(data, identities)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkImported(t, b)
+		})
+	}
+}
+
+// 不在接收者中的私钥无法解密
+func TestRecipientsOtherIdentity(t *testing.T) {
+	sshPub, _ := sshKeyPair(t, "")
+	_, otherSSH := sshKeyPair(t, "")
+	other, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipient, err := ParseRecipient(sshPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := EncodeFor(testBundle(t), []age.Recipient{recipient})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, identity := range map[string]string{"x25519": other.String(), "ssh": string(otherSSH)} {
+		identities, err := ParseIdentity([]byte(identity), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := DecodeWith(data, identities); err == nil {
+			t.Errorf("%s identity decrypted a bundle not encrypted for it", name)
+		}
+	}
+}
+
+func TestParseRecipientErrors(t *testing.T) {
+	for _, s := range []string{"", "age1invalid", "ssh-dss AAAA", "not a key"} {
+		if _, err := ParseRecipient(s); err == nil {
+			t.Errorf("ParseRecipient(%q) succeeded", s)
+		}
+	}
+	if _, err := ParseRecipients(strings.NewReader("# ok\nbogus\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("ParseRecipients error = %v, want line number", err)
+	}
+	if _, err := EncodeFor(testBundle(t), nil); err == nil {
+		t.Error("EncodeFor without recipients succeeded")
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"tssh/bundle"
+	"tssh/database"
+	"tssh/models"
+	"tssh/ssh"
+
+	"filippo.io/age"
+	"golang.org/x/term"
+)
+
+// envBundlePassphrase 非交互使用时提供导出文件口令的环境变量
+const envBundlePassphrase = "TSSH_BUNDLE_PASSPHRASE"
+
+// commands 命令行子命令, 未指定子命令时启动 TUI
+var commands = map[string]func(db database.Store, args []string) error{
+	"export": runExport,
+	"import": runImport,
+}
+
+// runExport 导出全部连接、身份和密钥
+// 未加密时不导出任何密码类字段
+func runExport(db database.Store, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	encrypt := fs.Bool("encrypt", false, "include secrets and encrypt the bundle with a passphrase")
+	var to, toFile pathList
+	fs.Var(&to, "to", "include secrets and encrypt to this age or ssh public key, can be repeated")
+	fs.Var(&toFile, "to-file", "include secrets and encrypt to the public keys in this file, can be repeated")
+	filter := fs.String("filter", "", "only export connections matching the keyword")
+	output := fs.String("o", "", "output file (default stdout)")
+	fs.Parse(args)
+
+	var recipients []age.Recipient
+	for _, s := range to {
+		recipient, err := bundle.ParseRecipient(s)
+		if err != nil {
+			return err
+		}
+		recipients = append(recipients, recipient)
+	}
+	for _, path := range toFile {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		parsed, err := bundle.ParseRecipients(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		recipients = append(recipients, parsed...)
+	}
+	if *encrypt && len(recipients) > 0 {
+		return errors.New("--encrypt cannot be used with --to or --to-file")
+	}
+
+	withSecrets := *encrypt || len(recipients) > 0
+	b, err := bundle.Export(db, withSecrets)
+	if err != nil {
+		return err
+	}
+	if *filter != "" {
+		b.Select(*filter)
+	}
+	var data []byte
+	switch {
+	case len(recipients) > 0:
+		data, err = bundle.EncodeFor(b, recipients)
+	case *encrypt:
+		var passphrase []byte
+		passphrase, err = readPassphrase("Bundle passphrase: ", true)
+		if err != nil {
+			return err
+		}
+		defer models.Zero(passphrase)
+		data, err = bundle.Encode(b, passphrase)
+	default:
+		fmt.Fprintln(os.Stderr, "Secrets are not exported without --encrypt or --to")
+		data, err = bundle.Encode(b, nil)
+	}
+	if err != nil {
+		return err
+	}
+	if !bytes.HasSuffix(data, []byte("\n")) {
+		data = append(data, '\n')
+	}
+	if *output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(*output, data, 0600)
+}
+
+// runImport 导入导出文件, --dry-run 时只输出将要发生的变化
+func runImport(db database.Store, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	strategy := fs.String("strategy", string(bundle.StrategyMerge), "how to handle existing entries: merge, overwrite or skip")
+	dryRun := fs.Bool("dry-run", false, "report what would change without modifying the store")
+	var identityFiles pathList
+	fs.Var(&identityFiles, "identity", "age or ssh private key for bundles encrypted to recipients (default ~/.ssh/id_ed25519 and ~/.ssh/id_rsa), can be repeated")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: tssh import [flags] <file>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	protection, err := bundle.Detect(data)
+	if err != nil {
+		return err
+	}
+	var b *bundle.Bundle
+	switch protection {
+	case bundle.Recipients:
+		identities, err := loadIdentities(identityFiles)
+		if err != nil {
+			return err
+		}
+		b, err = bundle.DecodeWith(data, identities)
+		if err != nil {
+			return err
+		}
+	case bundle.Passphrase:
+		passphrase, err := readPassphrase("Bundle passphrase: ", false)
+		if err != nil {
+			return err
+		}
+		defer models.Zero(passphrase)
+		b, err = bundle.Decode(data, passphrase)
+		if err != nil {
+			return err
+		}
+	default:
+		b, err = bundle.Decode(data, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	changes, err := bundle.Import(db, b, bundle.Strategy(*strategy), *dryRun)
+	for _, c := range changes {
+		fmt.Println(c)
+	}
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, c := range changes {
+		if c.Err != nil && c.Action != bundle.ActionSkip {
+			failed++
+		}
+	}
+	if *dryRun {
+		fmt.Println("Dry run, nothing was changed")
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d entries failed to import", failed)
+	}
+	return nil
+}
+
+// loadIdentities 读取用于解密的私钥, 未指定时使用默认的 SSH 私钥
+func loadIdentities(paths []string) ([]age.Identity, error) {
+	if len(paths) == 0 {
+		for _, path := range []string{"~/.ssh/id_ed25519", "~/.ssh/id_rsa"} {
+			path = ssh.ExpandPath(path)
+			if _, err := os.Stat(path); err == nil {
+				paths = append(paths, path)
+			}
+		}
+		if len(paths) == 0 {
+			return nil, errors.New("no identity found, use --identity to specify a private key")
+		}
+	}
+	var identities []age.Identity
+	for _, path := range paths {
+		data, err := os.ReadFile(ssh.ExpandPath(path))
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := bundle.ParseIdentity(data, func() ([]byte, error) {
+			return readTerminal(fmt.Sprintf("Passphrase for %s: ", path))
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		identities = append(identities, parsed...)
+	}
+	return identities, nil
+}
+
+// readPassphrase 从终端读取口令, confirm 为 true 时要求输入两次
+func readPassphrase(prompt string, confirm bool) ([]byte, error) {
+	if p := os.Getenv(envBundlePassphrase); p != "" {
+		return []byte(p), nil
+	}
+	passphrase, err := readTerminal(prompt)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(passphrase)) == 0 {
+		return nil, errors.New("passphrase must not be empty")
+	}
+	if !confirm {
+		return passphrase, nil
+	}
+	again, err := readTerminal("Repeat " + strings.ToLower(prompt[:1]) + prompt[1:])
+	defer models.Zero(again)
+	if err != nil {
+		models.Zero(passphrase)
+		return nil, err
+	}
+	if !bytes.Equal(passphrase, again) {
+		models.Zero(passphrase)
+		return nil, errors.New("passphrases do not match")
+	}
+	return passphrase, nil
+}
+
+// readTerminal 在终端中不回显地读取一行输入
+func readTerminal(prompt string) ([]byte, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read passphrase: %w", err)
+	}
+	defer tty.Close()
+
+	fmt.Fprint(tty, prompt)
+	value, err := term.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(tty)
+	return value, err
+}
@@ -0,0 +1,246 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config tssh 的配置
+// 优先级从低到高依次为: 默认值、配置文件、环境变量、命令行参数
+type Config struct {
+	// DataDir 数据目录, 保存数据库等文件
+	DataDir  string   `yaml:"data_dir,omitempty"`
+	Store    Store    `yaml:"store"`
+	Defaults Defaults `yaml:"defaults"`
+	Connect  Connect  `yaml:"connect"`
+	UI       UI       `yaml:"ui"`
+	// Keys 自定义按键, 第一层为按键映射名称, 第二层为按键名称
+	Keys map[string]map[string][]string `yaml:"keys,omitempty"`
+
+	// path 配置文件的路径
+	path string
+}
+
+// Store 连接存储的配置
+type Store struct {
+	// Kind 存储类型: sqlite, file 或 memory
+	Kind string `yaml:"kind,omitempty"`
+	// Path 数据库或清单文件的路径, 为空时保存在数据目录中
+	Path string `yaml:"path,omitempty"`
+	// Inventories 只读的共享清单文件
+	Inventories []string `yaml:"inventories,omitempty"`
+	// TrashRetention 回收站中的连接保留多久, 为 0 时永久保留
+	TrashRetention Duration `yaml:"trash_retention"`
+}
+
+// Defaults 新增连接时表单的默认值
+type Defaults struct {
+	Username   string `yaml:"username,omitempty"`
+	Port       int    `yaml:"port"`
+	Auth       string `yaml:"auth"` // password 或 key
+	PrivateKey string `yaml:"private_key,omitempty"`
+}
+
+// Connect 连接服务器时的行为
+type Connect struct {
+	// StrictHostKeyChecking 传给 ssh 的 StrictHostKeyChecking 选项
+	StrictHostKeyChecking string `yaml:"strict_host_key_checking"`
+	// DefaultKey 连接配置的私钥不存在时使用的私钥
+	DefaultKey string `yaml:"default_key"`
+	// ExtraArgs 追加给 ssh/sftp 的参数
+	ExtraArgs []string `yaml:"extra_args,omitempty"`
+	// ReturnToList 连接断开后回到连接列表, 而不是退出 tssh
+	ReturnToList bool `yaml:"return_to_list"`
+}
+
+// UI 界面选项
+type UI struct {
+	// TableHeight 连接列表的高度 (包括表头), 为 0 时填满终端
+	TableHeight int `yaml:"table_height"`
+	// Columns 连接列表显示的列及其顺序, 为空时使用默认的列
+	Columns []string `yaml:"columns,omitempty"`
+	// Sort 连接列表的排序列, 依次比较, 列名前加 "-" 表示降序
+	Sort []string `yaml:"sort,omitempty"`
+	// ColumnWidths 各列的宽度, 键为列名
+	ColumnWidths map[string]int `yaml:"column_widths,omitempty"`
+	// ConfirmDelete 删除连接前是否需要确认
+	ConfirmDelete bool `yaml:"confirm_delete"`
+	// Theme 主题名称或主题文件, auto 表示根据终端背景色选择
+	Theme string `yaml:"theme"`
+	// ShowDetails 启动时显示选中连接的详情面板
+	ShowDetails bool `yaml:"show_details"`
+	// ProbeHosts 详情面板是否连接主机检查可达性和主机密钥
+	ProbeHosts bool `yaml:"probe_hosts"`
+	// Mouse 启用鼠标操作, 此时界面使用全屏模式
+	Mouse bool `yaml:"mouse"`
+}
+
+// Duration 配置文件中以 "720h" 形式书写的时长
+type Duration time.Duration
+
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	v, err := time.ParseDuration(value.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", value.Line, err)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalYAML() (any, error) {
+	return time.Duration(d).String(), nil
+}
+
+// Default 返回默认配置
+func Default() *Config {
+	return &Config{
+		Store: Store{
+			Kind:           "sqlite",
+			TrashRetention: Duration(30 * 24 * time.Hour),
+		},
+		Defaults: Defaults{
+			Port: 22,
+			Auth: "password",
+		},
+		Connect: Connect{
+			StrictHostKeyChecking: "no",
+			DefaultKey:            "~/.ssh/id_rsa",
+		},
+		UI: UI{
+			ConfirmDelete: true,
+			Theme:         "auto",
+			ProbeHosts:    true,
+		},
+	}
+}
+
+// Dir 返回配置目录 $XDG_CONFIG_HOME/tssh, 未设置时为 ~/.config/tssh
+func Dir() (string, error) {
+	return xdgDir("XDG_CONFIG_HOME", ".config")
+}
+
+// DataDir 返回默认的数据目录 $XDG_DATA_HOME/tssh, 未设置时为 ~/.local/share/tssh
+func DataDir() (string, error) {
+	return xdgDir("XDG_DATA_HOME", filepath.Join(".local", "share"))
+}
+
+func xdgDir(env, fallback string) (string, error) {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "tssh"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, fallback, "tssh"), nil
+}
+
+// Load 读取配置文件并应用环境变量
+// path 为空时使用 TSSH_CONFIG 或配置目录中的 config.yaml, 默认位置的文件不存在时使用默认配置
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	explicit := path != ""
+	if path == "" {
+		path = os.Getenv("TSSH_CONFIG")
+		explicit = path != ""
+	}
+	if path == "" {
+		dir, err := Dir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(dir, "config.yaml")
+	}
+	cfg.path = path
+
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	case !errors.Is(err, fs.ErrNotExist) || explicit:
+		return nil, err
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// applyEnv 使用环境变量覆盖配置文件中的值
+func (c *Config) applyEnv() error {
+	if v := os.Getenv("TSSH_DATA_DIR"); v != "" {
+		c.DataDir = v
+	}
+	if v := os.Getenv("TSSH_STORE"); v != "" {
+		c.Store.Kind = v
+	}
+	if v := os.Getenv("TSSH_DB"); v != "" {
+		c.Store.Path = v
+	}
+	if v := os.Getenv("TSSH_STORE_PATH"); v != "" {
+		c.Store.Path = v
+	}
+	if v := os.Getenv("TSSH_INVENTORY"); v != "" {
+		c.Store.Inventories = filepath.SplitList(v)
+	}
+	if v := os.Getenv("TSSH_TRASH_RETENTION"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("TSSH_TRASH_RETENTION: %w", err)
+		}
+		c.Store.TrashRetention = Duration(d)
+	}
+	if v := os.Getenv("TSSH_DEFAULT_PORT"); v != "" {
+		port, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("TSSH_DEFAULT_PORT: %w", err)
+		}
+		c.Defaults.Port = port
+	}
+	if v := os.Getenv("TSSH_DEFAULT_USER"); v != "" {
+		c.Defaults.Username = v
+	}
+	if v := os.Getenv("TSSH_THEME"); v != "" {
+		c.UI.Theme = v
+	}
+	return nil
+}
+
+// Path 返回配置文件的路径
+func (c *Config) Path() string {
+	return c.path
+}
+
+// ThemeDir 返回自定义主题文件所在的目录, 位于配置文件旁的 themes 目录
+func (c *Config) ThemeDir() string {
+	return filepath.Join(filepath.Dir(c.path), "themes")
+}
+
+// Validate 检查配置的取值
+func (c *Config) Validate() error {
+	if c.Defaults.Port < 1 || c.Defaults.Port > 65535 {
+		return fmt.Errorf("invalid default port: %d", c.Defaults.Port)
+	}
+	switch c.Defaults.Auth {
+	case "password", "key":
+	default:
+		return fmt.Errorf("invalid default auth: %s, must be password or key", c.Defaults.Auth)
+	}
+	if c.Store.TrashRetention < 0 {
+		return errors.New("trash_retention must not be negative")
+	}
+	if c.UI.TableHeight < 0 {
+		return fmt.Errorf("invalid table_height: %d", c.UI.TableHeight)
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// isolate 将主目录和 XDG 目录指向临时目录, 并清除 TSSH_* 环境变量
+func isolate(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, env := range []string{"XDG_CONFIG_HOME", "XDG_DATA_HOME", "TSSH_CONFIG", "TSSH_DATA_DIR",
+		"TSSH_STORE", "TSSH_DB", "TSSH_STORE_PATH", "TSSH_INVENTORY", "TSSH_TRASH_RETENTION",
+		"TSSH_DEFAULT_PORT", "TSSH_DEFAULT_USER", "TSSH_THEME"} {
+		t.Setenv(env, "")
+	}
+	return home
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestXDGDirs(t *testing.T) {
+	home := isolate(t)
+	tests := []struct {
+		name       string
+		configHome string
+		dataHome   string
+		wantConfig string
+		wantData   string
+	}{
+		{"unset", "", "", filepath.Join(home, ".config", "tssh"), filepath.Join(home, ".local", "share", "tssh")},
+		{"set", "/xdg/config", "/xdg/data", "/xdg/config/tssh", "/xdg/data/tssh"},
+		// XDG 规范要求忽略相对路径
+		{"relative", "config", "data", filepath.Join(home, ".config", "tssh"), filepath.Join(home, ".local", "share", "tssh")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_CONFIG_HOME", tt.configHome)
+			t.Setenv("XDG_DATA_HOME", tt.dataHome)
+			if dir, err := Dir(); err != nil || dir != tt.wantConfig {
+				t.Errorf("Dir() = %q, %v, want %q", dir, err, tt.wantConfig)
+			}
+			if dir, err := DataDir(); err != nil || dir != tt.wantData {
+				t.Errorf("DataDir() = %q, %v, want %q", dir, err, tt.wantData)
+			}
+		})
+	}
+}
+
+func TestLoadLocation(t *testing.T) {
+	home := isolate(t)
+	xdg := filepath.Join(home, "xdg")
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	writeFile(t, filepath.Join(xdg, "tssh", "config.yaml"), "defaults:\n  username: xdg\n")
+	other := filepath.Join(home, "other.yaml")
+	writeFile(t, other, "defaults:\n  username: other\n")
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Defaults.Username != "xdg" || cfg.Path() != filepath.Join(xdg, "tssh", "config.yaml") {
+		t.Errorf("default location: user %q path %q", cfg.Defaults.Username, cfg.Path())
+	}
+
+	t.Setenv("TSSH_CONFIG", other)
+	if cfg, err = Load(""); err != nil || cfg.Defaults.Username != "other" {
+		t.Errorf("TSSH_CONFIG: %+v, %v", cfg, err)
+	}
+	// 命令行指定的路径优先于 TSSH_CONFIG, 且必须存在
+	if _, err := Load(filepath.Join(home, "missing.yaml")); err == nil {
+		t.Error("explicit missing config loaded")
+	}
+	t.Setenv("TSSH_CONFIG", filepath.Join(home, "missing.yaml"))
+	if _, err := Load(""); err == nil {
+		t.Error("missing TSSH_CONFIG loaded")
+	}
+}
+
+func TestLoadEnvPrecedence(t *testing.T) {
+	const file = `data_dir: /file/data
+store:
+  kind: file
+  path: /file/store.yaml
+defaults:
+  username: file
+  port: 2200
+ui:
+  theme: dark
+`
+	tests := []struct {
+		name  string
+		env   map[string]string
+		check func(*Config) bool
+	}{
+		{"file only", nil, func(c *Config) bool {
+			return c.DataDir == "/file/data" && c.Store.Kind == "file" && c.Store.Path == "/file/store.yaml" &&
+				c.Defaults.Username == "file" && c.Defaults.Port == 2200 && c.Defaults.Auth == "password" && c.UI.Theme == "dark"
+		}},
+		{"data dir", map[string]string{"TSSH_DATA_DIR": "/env/data"}, func(c *Config) bool {
+			return c.DataDir == "/env/data"
+		}},
+		{"store", map[string]string{"TSSH_STORE": "memory", "TSSH_DB": "/env/tssh.db"}, func(c *Config) bool {
+			return c.Store.Kind == "memory" && c.Store.Path == "/env/tssh.db"
+		}},
+		{"store path wins over db", map[string]string{"TSSH_DB": "/env/tssh.db", "TSSH_STORE_PATH": "/env/store.yaml"}, func(c *Config) bool {
+			return c.Store.Path == "/env/store.yaml"
+		}},
+		{"inventory", map[string]string{"TSSH_INVENTORY": "/a.yaml" + string(filepath.ListSeparator) + "/b.yaml"}, func(c *Config) bool {
+			return len(c.Store.Inventories) == 2 && c.Store.Inventories[1] == "/b.yaml"
+		}},
+		{"defaults", map[string]string{"TSSH_DEFAULT_PORT": "2222", "TSSH_DEFAULT_USER": "env", "TSSH_THEME": "light"}, func(c *Config) bool {
+			return c.Defaults.Port == 2222 && c.Defaults.Username == "env" && c.UI.Theme == "light"
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := isolate(t)
+			path := filepath.Join(home, "config.yaml")
+			writeFile(t, path, file)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			cfg, err := Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.check(cfg) {
+				t.Errorf("unexpected config: %+v", cfg)
+			}
+		})
+	}
+}
+
+func TestLoadInvalidEnv(t *testing.T) {
+	for _, env := range []string{"TSSH_DEFAULT_PORT", "TSSH_TRASH_RETENTION"} {
+		t.Run(env, func(t *testing.T) {
+			isolate(t)
+			t.Setenv(env, "abc")
+			if _, err := Load(""); err == nil {
+				t.Errorf("%s=abc accepted", env)
+			}
+		})
+	}
+}
+
+func TestPrepareDataDirMigratesLegacy(t *testing.T) {
+	tests := []struct {
+		name       string
+		newExists  bool
+		wantLegacy bool // 迁移后 ~/.xssh 是否仍然存在
+	}{
+		{"new dir missing", false, false},
+		{"new dir exists", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := isolate(t)
+			legacy := filepath.Join(home, legacyDir)
+			writeFile(t, filepath.Join(legacy, "connections.db"), "legacy")
+			dataDir := filepath.Join(home, ".local", "share", "tssh")
+			if tt.newExists {
+				writeFile(t, filepath.Join(dataDir, "connections.db"), "new")
+			}
+
+			cfg := Default()
+			if err := cfg.PrepareDataDir(); err != nil {
+				t.Fatal(err)
+			}
+			if cfg.DataDir != dataDir || cfg.Store.Path != filepath.Join(dataDir, "connections.db") {
+				t.Errorf("data dir %q, store %q", cfg.DataDir, cfg.Store.Path)
+			}
+			want := "legacy"
+			if tt.newExists {
+				want = "new"
+			}
+			if data, err := os.ReadFile(cfg.Store.Path); err != nil || string(data) != want {
+				t.Errorf("store content = %q, %v, want %q", data, err, want)
+			}
+			if _, err := os.Stat(legacy); (err == nil) != tt.wantLegacy {
+				t.Errorf("legacy dir exists = %v, want %v", err == nil, tt.wantLegacy)
+			}
+		})
+	}
+}
+
+// 显式配置的数据目录不做迁移
+func TestPrepareDataDirExplicit(t *testing.T) {
+	home := isolate(t)
+	writeFile(t, filepath.Join(home, legacyDir, "connections.db"), "legacy")
+	cfg := Default()
+	cfg.DataDir = "~/data"
+	cfg.Store.Kind = "file"
+	if err := cfg.PrepareDataDir(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DataDir != filepath.Join(home, "data") || cfg.Store.Path != filepath.Join(home, "data", "connections.yaml") {
+		t.Errorf("data dir %q, store %q", cfg.DataDir, cfg.Store.Path)
+	}
+	if _, err := os.Stat(filepath.Join(home, legacyDir)); err != nil {
+		t.Errorf("legacy dir moved: %v", err)
+	}
+}
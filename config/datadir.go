@@ -0,0 +1,78 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// legacyDir 旧版本使用的配置和数据目录
+const legacyDir = ".xssh"
+
+// PrepareDataDir 确定并创建数据目录, 未指定存储路径时使用数据目录中的默认文件
+// 未配置数据目录且旧版本的 ~/.xssh 存在时, 将其迁移到新的数据目录
+func (c *Config) PrepareDataDir() error {
+	if c.DataDir == "" {
+		dir, err := DataDir()
+		if err != nil {
+			return err
+		}
+		c.DataDir, err = migrateLegacy(dir)
+		if err != nil {
+			return err
+		}
+	}
+	c.DataDir = ExpandHome(c.DataDir)
+	if err := os.MkdirAll(c.DataDir, 0755); err != nil {
+		return err
+	}
+
+	if c.Store.Path == "" {
+		c.Store.Path = filepath.Join(c.DataDir, "connections.db")
+		if c.Store.Kind == "file" {
+			c.Store.Path = filepath.Join(c.DataDir, "connections.yaml")
+		}
+	}
+	c.Store.Path = ExpandHome(c.Store.Path)
+	for i, path := range c.Store.Inventories {
+		c.Store.Inventories[i] = ExpandHome(path)
+	}
+	return nil
+}
+
+// migrateLegacy 将 ~/.xssh 移动到 dir, 返回实际使用的数据目录
+// dir 已存在时不做迁移; 无法移动时 (例如跨文件系统) 继续使用旧目录
+func migrateLegacy(dir string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	legacy := filepath.Join(home, legacyDir)
+	if info, err := os.Stat(legacy); err != nil || !info.IsDir() {
+		return dir, nil
+	}
+	if _, err := os.Stat(dir); !errors.Is(err, fs.ErrNotExist) {
+		return dir, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return "", err
+	}
+	if err := os.Rename(legacy, dir); err != nil {
+		return legacy, nil
+	}
+	return dir, nil
+}
+
+// ExpandHome 将路径开头的 ~ 替换为用户主目录
+func ExpandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
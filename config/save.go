@@ -0,0 +1,129 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Save 将配置中 keys 指定的项 (如 "ui.columns") 写入配置文件
+// 只替换这些项, 文件中的其他内容和注释保持不变; 配置文件不存在时创建
+// 不写入整个配置, 以免把环境变量和命令行参数的值固化到文件中
+func (c *Config) Save(keys ...string) error {
+	var current yaml.Node
+	if err := current.Encode(c); err != nil {
+		return err
+	}
+
+	var doc yaml.Node
+	data, err := os.ReadFile(c.path)
+	switch {
+	case err == nil:
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("%s: %w", c.path, err)
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return err
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s: top level is not a mapping", c.path)
+	}
+
+	for _, key := range keys {
+		path := strings.Split(key, ".")
+		value := lookupNode(&current, path)
+		if value == nil {
+			// 值为空时被 omitempty 省略, 从文件中删除该项
+			deleteNode(root, path)
+			continue
+		}
+		setNode(root, path, value)
+	}
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, out.Bytes(), 0644)
+}
+
+// lookupNode 按路径查找映射中的值, 不存在时返回 nil
+func lookupNode(node *yaml.Node, path []string) *yaml.Node {
+	for _, name := range path {
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == name {
+				next = node.Content[i+1]
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		node = next
+	}
+	return node
+}
+
+// setNode 按路径设置映射中的值, 缺少的中间层自动创建, 替换时保留原值的注释
+func setNode(node *yaml.Node, path []string, value *yaml.Node) {
+	name, rest := path[0], path[1:]
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != name {
+			continue
+		}
+		old := node.Content[i+1]
+		if len(rest) == 0 {
+			value.HeadComment, value.LineComment, value.FootComment = old.HeadComment, old.LineComment, old.FootComment
+			node.Content[i+1] = value
+			return
+		}
+		if old.Kind != yaml.MappingNode {
+			node.Content[i+1] = &yaml.Node{Kind: yaml.MappingNode}
+		}
+		setNode(node.Content[i+1], rest, value)
+		return
+	}
+	next := value
+	if len(rest) > 0 {
+		next = &yaml.Node{Kind: yaml.MappingNode}
+		setNode(next, rest, value)
+	}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, next)
+}
+
+// deleteNode 按路径删除映射中的值
+func deleteNode(node *yaml.Node, path []string) {
+	parent := lookupNode(node, path[:len(path)-1])
+	if parent == nil || parent.Kind != yaml.MappingNode {
+		return
+	}
+	name := path[len(path)-1]
+	for i := 0; i+1 < len(parent.Content); i += 2 {
+		if parent.Content[i].Value == name {
+			parent.Content = append(parent.Content[:i], parent.Content[i+2:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSave(t *testing.T) {
+	const original = `# tssh 配置
+store:
+  kind: sqlite # 存储类型
+  future_option: 1
+ui:
+  # 排序列
+  sort:
+    - name
+  theme: dark
+plugins:
+  enabled: true
+`
+	tests := []struct {
+		name     string
+		existing string
+		update   func(*Config)
+		keys     []string
+		contains []string
+		missing  []string
+	}{
+		{
+			name:     "replace keeps comments and unknown keys",
+			existing: original,
+			update:   func(c *Config) { c.UI.Sort = []string{"-host"} },
+			keys:     []string{"ui.sort"},
+			contains: []string{"# tssh 配置", "kind: sqlite # 存储类型", "future_option: 1", "# 排序列", "- -host", "theme: dark", "plugins:", "enabled: true"},
+			missing:  []string{"- name"},
+		},
+		{
+			name:     "only listed keys are written",
+			existing: original,
+			update: func(c *Config) {
+				c.UI.Columns = []string{"name", "host"}
+				c.UI.Theme = "light"
+				c.Defaults.Port = 2222
+			},
+			keys:     []string{"ui.columns"},
+			contains: []string{"columns:", "- host", "theme: dark"},
+			missing:  []string{"light", "2222"},
+		},
+		{
+			name:     "empty value is removed",
+			existing: original,
+			update:   func(c *Config) { c.UI.Sort = nil },
+			keys:     []string{"ui.sort"},
+			contains: []string{"theme: dark", "future_option: 1"},
+			missing:  []string{"sort:", "- name"},
+		},
+		{
+			name:     "missing sections are created",
+			existing: "store:\n  kind: sqlite\n",
+			update:   func(c *Config) { c.UI.ColumnWidths = map[string]int{"name": 30} },
+			keys:     []string{"ui.column_widths"},
+			contains: []string{"kind: sqlite", "ui:", "column_widths:", "name: 30"},
+		},
+		{
+			name:     "new file",
+			update:   func(c *Config) { c.UI.Sort = []string{"name"} },
+			keys:     []string{"ui.sort"},
+			contains: []string{"ui:", "sort:", "- name"},
+			missing:  []string{"store:", "defaults:"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolate(t)
+			path := filepath.Join(t.TempDir(), "tssh", "config.yaml")
+			if tt.existing != "" {
+				writeFile(t, path, tt.existing)
+			}
+			t.Setenv("TSSH_CONFIG", path)
+			cfg := Default()
+			cfg.path = path
+			if tt.existing != "" {
+				var err error
+				if cfg, err = Load(""); err != nil {
+					t.Fatal(err)
+				}
+			}
+			tt.update(cfg)
+			if err := cfg.Save(tt.keys...); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(string(data), s) {
+					t.Errorf("saved config lacks %q:\n%s", s, data)
+				}
+			}
+			for _, s := range tt.missing {
+				if strings.Contains(string(data), s) {
+					t.Errorf("saved config contains %q:\n%s", s, data)
+				}
+			}
+			// 保存后的文件仍能正常读取
+			if _, err := Load(path); err != nil {
+				t.Errorf("reload: %v", err)
+			}
+		})
+	}
+}
+
+// 环境变量的值不会被写入配置文件
+func TestSaveDoesNotPersistEnv(t *testing.T) {
+	isolate(t)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, "defaults:\n  username: file\n")
+	t.Setenv("TSSH_DEFAULT_USER", "env")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.UI.Sort = []string{"host"}
+	if err := cfg.Save("ui.sort"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "env") || !strings.Contains(string(data), "username: file") {
+		t.Errorf("saved config:\n%s", data)
+	}
+}
@@ -1,8 +1,14 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
 	"tssh/models"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -10,10 +16,37 @@ import (
 
 type DB struct {
 	*sql.DB
+	// watch 专用于读取 data_version 的连接, 该值只在同一连接上比较才有意义
+	watch   *sql.Conn
+	watchMu sync.Mutex
 }
 
+// ErrConflict 连接在读取后被其他 tssh 实例修改
+var ErrConflict = errors.New("connection was modified by another tssh instance, reload and try again")
+
+// queryer 由 *sql.DB 和 *sql.Tx 实现, 使查询可以在事务中执行
+type queryer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// connColumns 查询连接时使用的列, 顺序需与 scanConn 保持一致
+// 引用身份的连接显示身份的用户名
+const connColumns = "c.id, c.name, c.host, c.port, COALESCE(i.username, c.username), c.auth_type, c.password, c.private_key, c.key_data, c.key_passphrase, c.identity_id, c.totp_secret, c.secret_backend, c.secret_ref, c.notes, c.meta, c.version, c.last_used"
+
+const connFrom = "ssh_connections c LEFT JOIN ssh_identities i ON i.id = c.identity_id"
+
+// notDeleted 排除回收站中连接的查询条件
+const notDeleted = "c.deleted_at = ''"
+
+// NewDB 打开 SQLite 数据库
+// 使用 WAL 模式和忙等待超时, 多个 tssh 实例可以同时读写同一个数据库
+// 写事务开始时即获取写锁, 避免读事务升级为写事务时发生死锁
 func NewDB(dbPath string) (*DB, error) {
-	db, err := sql.Open("sqlite3", dbPath)
+	// 路径中可能含有 ?、# 或 %, 需要转义后才能作为 URI 使用
+	dsn := "file:" + (&url.URL{Path: dbPath}).EscapedPath() + "?_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate"
+	db, err := sql.Open("sqlite3", dsn)
 	if err != nil {
 		return nil, err
 	}
@@ -26,7 +59,11 @@ func NewDB(dbPath string) (*DB, error) {
 		return nil, err
 	}
 
-	return &DB{db}, nil
+	if err := migrate(db); err != nil {
+		return nil, err
+	}
+
+	return &DB{DB: db}, nil
 }
 
 func createTables(db *sql.DB) error {
@@ -40,124 +77,368 @@ func createTables(db *sql.DB) error {
 		auth_type INTEGER NOT NULL, -- 1: password, 2: key
 		password TEXT,
 		private_key TEXT
-	);`
+	);
+	CREATE TABLE IF NOT EXISTS ssh_keys (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		key_type TEXT NOT NULL,
+		private_key TEXT NOT NULL,
+		public_key TEXT NOT NULL,
+		fingerprint TEXT NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS ssh_identities (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		username TEXT NOT NULL,
+		auth_type INTEGER NOT NULL, -- 1: password, 2: key
+		password TEXT NOT NULL DEFAULT '',
+		private_key TEXT NOT NULL DEFAULT '',
+		key_data TEXT NOT NULL DEFAULT '',
+		key_passphrase TEXT NOT NULL DEFAULT ''
+	);
+	CREATE TABLE IF NOT EXISTS connection_identities (
+		connection_id INTEGER NOT NULL,
+		identity_id INTEGER NOT NULL,
+		PRIMARY KEY (connection_id, identity_id)
+	);
+	CREATE TABLE IF NOT EXISTS connection_overrides (
+		source TEXT NOT NULL,
+		name TEXT NOT NULL,
+		username TEXT NOT NULL DEFAULT '',
+		private_key TEXT NOT NULL DEFAULT '',
+		PRIMARY KEY (source, name)
+	);
+	CREATE TABLE IF NOT EXISTS connection_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		connection_id INTEGER NOT NULL,
+		action TEXT NOT NULL, -- add, update, delete, revert, restore
+		name TEXT NOT NULL,
+		changes TEXT NOT NULL DEFAULT '', -- 字段变化的 JSON, 密码类字段已屏蔽
+		before TEXT NOT NULL DEFAULT '', -- 修改前连接的 JSON, 密码类字段为密文
+		created_at TEXT NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_connection_history_connection ON connection_history (connection_id);
+	CREATE TABLE IF NOT EXISTS identity_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		identity_id INTEGER NOT NULL,
+		action TEXT NOT NULL, -- update, revert
+		name TEXT NOT NULL,
+		changes TEXT NOT NULL DEFAULT '',
+		before TEXT NOT NULL DEFAULT '', -- 修改前身份的 JSON, 密码类字段为密文
+		created_at TEXT NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_identity_history_identity ON identity_history (identity_id);`
 
 	_, err := db.Exec(query)
 	return err
 }
 
-func (db *DB) GetAllConnections() ([]models.ConnInfo, error) {
-	rows, err := db.Query("SELECT * FROM ssh_connections order by name")
+// migrate 为旧版本数据库补充新增的列
+func migrate(db *sql.DB) error {
+	columns := []struct {
+		table, name, def string
+	}{
+		{"ssh_connections", "key_data", "TEXT NOT NULL DEFAULT ''"},
+		{"ssh_connections", "key_passphrase", "TEXT NOT NULL DEFAULT ''"},
+		{"ssh_connections", "identity_id", "INTEGER NOT NULL DEFAULT 0"},
+		{"ssh_connections", "totp_secret", "TEXT NOT NULL DEFAULT ''"},
+		{"ssh_connections", "secret_backend", "TEXT NOT NULL DEFAULT ''"},
+		{"ssh_connections", "secret_ref", "TEXT NOT NULL DEFAULT ''"},
+		{"ssh_connections", "version", "INTEGER NOT NULL DEFAULT 0"},
+		{"ssh_connections", "updated_at", "TEXT NOT NULL DEFAULT ''"},
+		{"ssh_connections", "deleted_at", "TEXT NOT NULL DEFAULT ''"}, // 不为空表示连接在回收站中
+		{"ssh_connections", "last_used", "TEXT NOT NULL DEFAULT ''"},
+		{"ssh_connections", "notes", "TEXT NOT NULL DEFAULT ''"},
+		{"ssh_connections", "meta", "TEXT NOT NULL DEFAULT ''"}, // 自定义信息的 JSON
+	}
+	for _, c := range columns {
+		if err := addColumnIfMissing(db, c.table, c.name, c.def); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func addColumnIfMissing(db *sql.DB, table, column, def string) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
-	var connections []models.ConnInfo
 	for rows.Next() {
-		var conn models.ConnInfo
-		err := rows.Scan(
-			&conn.ID,
-			&conn.Name,
-			&conn.Host,
-			&conn.Port,
-			&conn.Username,
-			&conn.AuthType,
-			&conn.Password,
-			&conn.PrivateKey,
+		var (
+			cid       int
+			name      string
+			ctype     string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
 		)
-		if err != nil {
-			return nil, err
+		if err := rows.Scan(&cid, &name, &ctype, &notNull, &dfltValue, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
 		}
-		connections = append(connections, conn)
 	}
-	return connections, nil
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, def))
+	return err
+}
+
+type scanner interface {
+	Scan(dest ...any) error
 }
-func (db *DB) GetConnection(id int64) (models.ConnInfo, error) {
-	row := db.QueryRow("SELECT * FROM ssh_connections WHERE id = ?", id)
 
+func scanConn(s scanner) (models.ConnInfo, error) {
 	var conn models.ConnInfo
-	err := row.Scan(
+	var password, privateKey sql.NullString
+	var meta, lastUsed string
+	err := s.Scan(
 		&conn.ID,
 		&conn.Name,
 		&conn.Host,
 		&conn.Port,
 		&conn.Username,
 		&conn.AuthType,
-		&conn.Password,
-		&conn.PrivateKey,
+		&password,
+		&privateKey,
+		&conn.KeyData,
+		&conn.KeyPassphrase,
+		&conn.IdentityID,
+		&conn.TOTPSecret,
+		&conn.SecretBackend,
+		&conn.SecretRef,
+		&conn.Notes,
+		&meta,
+		&conn.Version,
+		&lastUsed,
 	)
 	if err != nil {
-		return models.ConnInfo{}, err
+		return conn, err
+	}
+	conn.Password = password.String
+	conn.PrivateKey = privateKey.String
+	if lastUsed != "" {
+		conn.LastUsed, _ = time.Parse(time.RFC3339, lastUsed)
+	}
+	if meta != "" {
+		if err := json.Unmarshal([]byte(meta), &conn.Meta); err != nil {
+			return conn, fmt.Errorf("connection %d: invalid meta: %w", conn.ID, err)
+		}
 	}
 	return conn, nil
 }
 
-func (db *DB) AddConnection(conn models.ConnInfo) error {
-	if conn.AuthType == models.UsePass {
-		if conn.Password == "" {
-			return errors.New("password is required for password authentication")
+// encodeMeta 将自定义信息编码为 JSON, 没有自定义信息时为空字符串
+func encodeMeta(meta map[string]string) string {
+	if len(meta) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(meta)
+	return string(data)
+}
+
+// withTx 在事务中执行 fn, fn 返回错误时回滚
+func (db *DB) withTx(fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// DataVersion 返回数据库的 data_version, 其他连接或进程提交修改后该值发生变化
+func (db *DB) DataVersion() (int64, error) {
+	db.watchMu.Lock()
+	defer db.watchMu.Unlock()
+	if db.watch == nil {
+		conn, err := db.Conn(context.Background())
+		if err != nil {
+			return 0, err
 		}
-		var err error
-		conn.Password, err = models.EncryptString(conn.Password)
+		db.watch = conn
+	}
+	var version int64
+	err := db.watch.QueryRowContext(context.Background(), "PRAGMA data_version").Scan(&version)
+	return version, err
+}
+
+func (db *DB) Close() error {
+	db.watchMu.Lock()
+	if db.watch != nil {
+		db.watch.Close()
+		db.watch = nil
+	}
+	db.watchMu.Unlock()
+	return db.DB.Close()
+}
+
+func (db *DB) GetAllConnections() ([]models.ConnInfo, error) {
+	rows, err := db.Query("SELECT " + connColumns + " FROM " + connFrom + " WHERE " + notDeleted + " order by c.name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var connections []models.ConnInfo
+	for rows.Next() {
+		conn, err := scanConn(rows)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		connections = append(connections, conn)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
+	if err := loadExtraIdentities(db, connections); err != nil {
+		return nil, err
+	}
+	return connections, nil
+}
+func (db *DB) SearchConnections(keyword string) ([]models.ConnInfo, error) {
+	connections, err := db.GetAllConnections()
+	if err != nil {
+		return nil, err
+	}
+	return filterConnections(connections, keyword), nil
+}
 
-	query := `
-	INSERT INTO ssh_connections (name, host, port, username, auth_type, password, private_key)
-	VALUES (?, ?, ?, ?, ?, ?, ?)`
-
-	_, err := db.Exec(query,
-		conn.Name,
-		conn.Host,
-		conn.Port,
-		conn.Username,
-		conn.AuthType,
-		conn.Password,
-		conn.PrivateKey,
-	)
-	return err
+func (db *DB) GetConnection(id int64) (models.ConnInfo, error) {
+	return getConnection(db, id)
 }
 
-func (db *DB) UpdateConnection(conn models.ConnInfo) error {
-	if conn.AuthType == models.UsePass && conn.Password != "" {
-		var err error
-		conn.Password, err = models.EncryptString(conn.Password)
+func getConnection(q queryer, id int64) (models.ConnInfo, error) {
+	row := q.QueryRow("SELECT "+connColumns+" FROM "+connFrom+" WHERE c.id = ? AND "+notDeleted, id)
+
+	conn, err := scanConn(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.ConnInfo{}, ErrNotFound
+	}
+	if err != nil {
+		return models.ConnInfo{}, err
+	}
+	conns := []models.ConnInfo{conn}
+	if err := loadExtraIdentities(q, conns); err != nil {
+		return models.ConnInfo{}, err
+	}
+	return conns[0], nil
+}
+
+func (db *DB) AddConnection(conn models.ConnInfo) error {
+	if err := prepareNewConn(&conn); err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO ssh_connections (name, host, port, username, auth_type, password, private_key, key_data, key_passphrase, identity_id, totp_secret, secret_backend, secret_ref, notes, meta, version, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 1, CURRENT_TIMESTAMP)`
+
+	return db.withTx(func(tx *sql.Tx) error {
+		res, err := tx.Exec(query,
+			conn.Name,
+			conn.Host,
+			conn.Port,
+			conn.Username,
+			conn.AuthType,
+			conn.Password,
+			conn.PrivateKey,
+			conn.KeyData,
+			conn.KeyPassphrase,
+			conn.IdentityID,
+			conn.TOTPSecret,
+			conn.SecretBackend,
+			conn.SecretRef,
+			conn.Notes,
+			encodeMeta(conn.Meta),
+		)
 		if err != nil {
 			return err
 		}
-	} else {
-		oldConn, err := db.GetConnection(conn.ID)
+		id, err := res.LastInsertId()
 		if err != nil {
 			return err
 		}
-		if conn.AuthType == models.UsePass && conn.Password == "" && oldConn.Password == "" {
-			return errors.New("password is required for password authentication")
+		if err := setExtraIdentities(tx, id, conn.ExtraIdentityIDs); err != nil {
+			return err
 		}
-		conn.Password = oldConn.Password
-	}
+		return recordHistory(tx, models.HistoryAdd, id, nil, &conn)
+	})
+}
 
+// UpdateConnection 更新连接信息, 密码字段的处理见 prepareUpdatedConn
+// conn.Version 与数据库中的版本不一致时说明连接已被其他实例修改, 返回 ErrConflict
+func (db *DB) UpdateConnection(conn models.ConnInfo) error {
 	query := `
 	UPDATE ssh_connections
-	SET name = ?, host = ?, port = ?, username = ?, auth_type = ?, password = ?, private_key = ?
-	WHERE id = ?`
-
-	_, err := db.Exec(query,
-		conn.Name,
-		conn.Host,
-		conn.Port,
-		conn.Username,
-		conn.AuthType,
-		conn.Password,
-		conn.PrivateKey,
-		conn.ID,
-	)
-	return err
+	SET name = ?, host = ?, port = ?, username = ?, auth_type = ?, password = ?, private_key = ?, key_data = ?, key_passphrase = ?, identity_id = ?, totp_secret = ?, secret_backend = ?, secret_ref = ?, notes = ?, meta = ?,
+		version = version + 1, updated_at = CURRENT_TIMESTAMP
+	WHERE id = ? AND version = ?`
+
+	return db.withTx(func(tx *sql.Tx) error {
+		oldConn, err := getConnection(tx, conn.ID)
+		if err != nil {
+			return err
+		}
+		if oldConn.Version != conn.Version {
+			return ErrConflict
+		}
+		if err := prepareUpdatedConn(&conn, oldConn); err != nil {
+			return err
+		}
+
+		res, err := tx.Exec(query,
+			conn.Name,
+			conn.Host,
+			conn.Port,
+			conn.Username,
+			conn.AuthType,
+			conn.Password,
+			conn.PrivateKey,
+			conn.KeyData,
+			conn.KeyPassphrase,
+			conn.IdentityID,
+			conn.TOTPSecret,
+			conn.SecretBackend,
+			conn.SecretRef,
+			conn.Notes,
+			encodeMeta(conn.Meta),
+			conn.ID,
+			conn.Version,
+		)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return ErrConflict
+		}
+		if err := setExtraIdentities(tx, conn.ID, conn.ExtraIdentityIDs); err != nil {
+			return err
+		}
+		return recordHistory(tx, models.HistoryUpdate, conn.ID, &oldConn, &conn)
+	})
 }
 
+// DeleteConnection 将连接移入回收站, 可通过 RestoreConnection 恢复
 func (db *DB) DeleteConnection(id int64) error {
-	_, err := db.Exec("DELETE FROM ssh_connections WHERE id = ?", id)
-	return err
+	return db.withTx(func(tx *sql.Tx) error {
+		old, err := getConnection(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := trashConn(tx, id); err != nil {
+			return err
+		}
+		return recordHistory(tx, models.HistoryDelete, id, &old, nil)
+	})
 }
@@ -0,0 +1,32 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 路径中的特殊字符不能被当作 URI 的查询参数或片段
+func TestNewDBEscapesPath(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "a?b#c%41d")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "tssh.db")
+	db, err := NewDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var mode string
+	if err := db.QueryRow("PRAGMA journal_mode").Scan(&mode); err != nil {
+		t.Fatal(err)
+	}
+	if mode != "wal" {
+		t.Errorf("journal_mode = %q, want wal", mode)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("database not created at %s: %v", path, err)
+	}
+}
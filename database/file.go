@@ -0,0 +1,196 @@
+package database
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"tssh/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileStore 将连接保存在 JSON 或 YAML 文件中, 便于纳入 git 管理
+// 根据文件扩展名 (.yaml/.yml) 选择 YAML 格式, 其余按 JSON 处理
+// 每次操作都重新读取文件, 以便感知外部对文件的修改
+type FileStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{path: path}
+	if _, err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileStore) isYAML() bool {
+	ext := strings.ToLower(filepath.Ext(s.path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+func (s *FileStore) load() (*connList, error) {
+	list := &connList{}
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return list, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if s.isYAML() {
+		data, err = yamlToJSON(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(data) == 0 {
+		return list, nil
+	}
+	if err := json.Unmarshal(data, list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// save 先写入临时文件再替换, 避免写入中断导致文件损坏
+func (s *FileStore) save(list *connList) error {
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	if s.isYAML() {
+		data, err = jsonToYAML(data)
+		if err != nil {
+			return err
+		}
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".tssh-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// modify 读取文件, 执行修改后写回
+func (s *FileStore) modify(fn func(list *connList) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list, err := s.load()
+	if err != nil {
+		return err
+	}
+	if err := fn(list); err != nil {
+		return err
+	}
+	return s.save(list)
+}
+
+func (s *FileStore) GetAllConnections() ([]models.ConnInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return list.all(), nil
+}
+
+func (s *FileStore) GetConnection(id int64) (models.ConnInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list, err := s.load()
+	if err != nil {
+		return models.ConnInfo{}, err
+	}
+	return list.get(id)
+}
+
+func (s *FileStore) AddConnection(conn models.ConnInfo) error {
+	return s.modify(func(list *connList) error {
+		return list.add(conn)
+	})
+}
+
+func (s *FileStore) UpdateConnection(conn models.ConnInfo) error {
+	return s.modify(func(list *connList) error {
+		return list.update(conn)
+	})
+}
+
+func (s *FileStore) DeleteConnection(id int64) error {
+	return s.modify(func(list *connList) error {
+		return list.delete(id)
+	})
+}
+
+func (s *FileStore) SearchConnections(keyword string) ([]models.ConnInfo, error) {
+	connections, err := s.GetAllConnections()
+	if err != nil {
+		return nil, err
+	}
+	return filterConnections(connections, keyword), nil
+}
+
+// DataVersion 返回文件的修改时间, 文件不存在时返回 0
+func (s *FileStore) DataVersion() (int64, error) {
+	info, err := os.Stat(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return info.ModTime().UnixNano(), nil
+}
+
+func (s *FileStore) Close() error {
+	return nil
+}
+
+// yamlToJSON 将 YAML 转换为 JSON, 使结构体只需维护 json 标签
+func yamlToJSON(data []byte) ([]byte, error) {
+	var v any
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
+
+func jsonToYAML(data []byte) ([]byte, error) {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,257 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+	"tssh/models"
+)
+
+const historyColumns = "id, connection_id, action, name, changes, before, created_at"
+
+// recordHistory 记录一次连接修改, before 和 after 为修改前后保存在数据库中的连接
+// 没有任何字段变化的更新不做记录
+func recordHistory(q queryer, action string, connID int64, before, after *models.ConnInfo) error {
+	changes := models.DiffConn(before, after)
+	if action == models.HistoryUpdate && len(changes) == 0 {
+		return nil
+	}
+	name := ""
+	if after != nil {
+		name = after.Name
+	} else if before != nil {
+		name = before.Name
+	}
+	changesJSON, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	beforeJSON := ""
+	if before != nil {
+		data, err := json.Marshal(before)
+		if err != nil {
+			return err
+		}
+		beforeJSON = string(data)
+	}
+	_, err = q.Exec("INSERT INTO connection_history (connection_id, action, name, changes, before, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		connID, action, name, string(changesJSON), beforeJSON, time.Now().UTC().Format(time.RFC3339))
+	return err
+}
+
+func scanHistory(s scanner) (models.HistoryEntry, error) {
+	var entry models.HistoryEntry
+	var changes, before, createdAt string
+	err := s.Scan(&entry.ID, &entry.ConnectionID, &entry.Action, &entry.Name, &changes, &before, &createdAt)
+	if err != nil {
+		return entry, err
+	}
+	if err := json.Unmarshal([]byte(changes), &entry.Changes); err != nil {
+		return entry, err
+	}
+	if before != "" {
+		entry.Before = &models.ConnInfo{}
+		if err := json.Unmarshal([]byte(before), entry.Before); err != nil {
+			return entry, err
+		}
+	}
+	entry.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	return entry, nil
+}
+
+func (db *DB) queryHistory(query string, args ...any) ([]models.HistoryEntry, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.HistoryEntry
+	for rows.Next() {
+		entry, err := scanHistory(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// ConnectionHistory 返回连接的修改记录, 最近的在前
+func (db *DB) ConnectionHistory(connID int64) ([]models.HistoryEntry, error) {
+	return db.queryHistory("SELECT "+historyColumns+" FROM connection_history WHERE connection_id = ? ORDER BY id DESC", connID)
+}
+
+// DeletedConnections 返回已删除连接最后一次删除的记录, 最近的在前
+func (db *DB) DeletedConnections() ([]models.HistoryEntry, error) {
+	return db.queryHistory(`SELECT ` + historyColumns + ` FROM connection_history h
+	WHERE action = '` + models.HistoryDelete + `'
+	AND NOT EXISTS (SELECT 1 FROM ssh_connections c WHERE c.id = h.connection_id AND ` + notDeleted + `)
+	AND id = (SELECT MAX(id) FROM connection_history WHERE connection_id = h.connection_id)
+	ORDER BY id DESC`)
+}
+
+// RevertHistory 将连接恢复到该记录修改之前的状态
+// 撤销新增即将连接移入回收站, 撤销删除即恢复被删除的连接, 撤销操作本身也会被记录
+func (db *DB) RevertHistory(entryID int64) error {
+	return db.withTx(func(tx *sql.Tx) error {
+		entry, err := scanHistory(tx.QueryRow("SELECT "+historyColumns+" FROM connection_history WHERE id = ?", entryID))
+		if err != nil {
+			return err
+		}
+		var current *models.ConnInfo
+		conn, err := getConnection(tx, entry.ConnectionID)
+		switch {
+		case err == nil:
+			current = &conn
+		case !errors.Is(err, ErrNotFound):
+			return err
+		}
+
+		if entry.Before == nil {
+			if current == nil {
+				return nil
+			}
+			if err := trashConn(tx, entry.ConnectionID); err != nil {
+				return err
+			}
+			return recordHistory(tx, models.HistoryRevert, entry.ConnectionID, current, nil)
+		}
+
+		restored := *entry.Before
+		restored.ID = entry.ConnectionID
+		if current != nil {
+			restored.Version = current.Version + 1
+		} else {
+			restored.Version++
+		}
+		// 回收站中的连接仍然存在, 恢复时直接覆盖
+		var exists bool
+		if err := tx.QueryRow("SELECT COUNT(*) > 0 FROM ssh_connections WHERE id = ?", entry.ConnectionID).Scan(&exists); err != nil {
+			return err
+		}
+		if err := restoreConn(tx, restored, exists); err != nil {
+			return err
+		}
+		return recordHistory(tx, models.HistoryRevert, entry.ConnectionID, current, &restored)
+	})
+}
+
+// restoreConn 按原样写入连接, 其中的密码类字段已经是密文
+// exists 为 false 时使用原 ID 重新插入已被彻底删除的连接, 为 true 时同时将连接移出回收站
+func restoreConn(q queryer, conn models.ConnInfo, exists bool) error {
+	query := `
+	INSERT INTO ssh_connections (name, host, port, username, auth_type, password, private_key, key_data, key_passphrase, identity_id, totp_secret, secret_backend, secret_ref, notes, meta, version, updated_at, id)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, ?)`
+	if exists {
+		query = `
+		UPDATE ssh_connections
+		SET name = ?, host = ?, port = ?, username = ?, auth_type = ?, password = ?, private_key = ?, key_data = ?, key_passphrase = ?, identity_id = ?, totp_secret = ?, secret_backend = ?, secret_ref = ?, notes = ?, meta = ?,
+			version = ?, updated_at = CURRENT_TIMESTAMP, deleted_at = ''
+		WHERE id = ?`
+	}
+	_, err := q.Exec(query,
+		conn.Name,
+		conn.Host,
+		conn.Port,
+		conn.Username,
+		conn.AuthType,
+		conn.Password,
+		conn.PrivateKey,
+		conn.KeyData,
+		conn.KeyPassphrase,
+		conn.IdentityID,
+		conn.TOTPSecret,
+		conn.SecretBackend,
+		conn.SecretRef,
+		conn.Notes,
+		encodeMeta(conn.Meta),
+		conn.Version,
+		conn.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return setExtraIdentities(q, conn.ID, conn.ExtraIdentityIDs)
+}
+
+const identityHistoryColumns = "id, identity_id, action, name, changes, before, created_at"
+
+// recordIdentityHistory 记录一次身份修改, before 和 after 为修改前后保存在数据库中的身份
+// 没有任何字段变化的修改不做记录
+func recordIdentityHistory(q queryer, action string, before, after models.Identity) error {
+	changes := models.DiffIdentity(before, after)
+	if len(changes) == 0 {
+		return nil
+	}
+	changesJSON, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	beforeJSON, err := json.Marshal(before)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec("INSERT INTO identity_history (identity_id, action, name, changes, before, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		after.ID, action, after.Name, string(changesJSON), string(beforeJSON), time.Now().UTC().Format(time.RFC3339))
+	return err
+}
+
+func scanIdentityHistory(s scanner) (models.HistoryEntry, error) {
+	var entry models.HistoryEntry
+	var changes, before, createdAt string
+	err := s.Scan(&entry.ID, &entry.IdentityID, &entry.Action, &entry.Name, &changes, &before, &createdAt)
+	if err != nil {
+		return entry, err
+	}
+	if err := json.Unmarshal([]byte(changes), &entry.Changes); err != nil {
+		return entry, err
+	}
+	entry.BeforeIdentity = &models.Identity{}
+	if err := json.Unmarshal([]byte(before), entry.BeforeIdentity); err != nil {
+		return entry, err
+	}
+	entry.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	return entry, nil
+}
+
+// IdentityHistory 返回身份的修改记录, 最近的在前
+func (db *DB) IdentityHistory(identityID int64) ([]models.HistoryEntry, error) {
+	rows, err := db.Query("SELECT "+identityHistoryColumns+" FROM identity_history WHERE identity_id = ? ORDER BY id DESC", identityID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.HistoryEntry
+	for rows.Next() {
+		entry, err := scanIdentityHistory(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// RevertIdentityHistory 将身份恢复到该记录修改之前的状态, 引用该身份的连接随之生效
+// 撤销操作本身也会被记录
+func (db *DB) RevertIdentityHistory(entryID int64) error {
+	return db.withTx(func(tx *sql.Tx) error {
+		entry, err := scanIdentityHistory(tx.QueryRow("SELECT "+identityHistoryColumns+" FROM identity_history WHERE id = ?", entryID))
+		if err != nil {
+			return err
+		}
+		current, err := getIdentity(tx, entry.IdentityID)
+		if err != nil {
+			return err
+		}
+		restored := *entry.BeforeIdentity
+		restored.ID = entry.IdentityID
+		if err := writeIdentity(tx, restored); err != nil {
+			return err
+		}
+		return recordIdentityHistory(tx, models.HistoryRevert, current, restored)
+	})
+}
@@ -0,0 +1,174 @@
+package database
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"tssh/models"
+)
+
+func openDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := NewDB(filepath.Join(t.TempDir(), "tssh.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func history(t *testing.T, db *DB, id int64) []models.HistoryEntry {
+	t.Helper()
+	entries, err := db.ConnectionHistory(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func TestHistoryRecordsChanges(t *testing.T) {
+	db := openDB(t)
+	if err := db.AddConnection(testConn("web")); err != nil {
+		t.Fatal(err)
+	}
+	conn := mustFind(t, db, "web")
+	// 没有变化的更新不记录, 密码为空表示保留原密码
+	conn.Password = ""
+	if err := db.UpdateConnection(conn); err != nil {
+		t.Fatal(err)
+	}
+	conn = mustFind(t, db, "web")
+	conn.Host = "10.0.0.1"
+	conn.Password = "new-pass"
+	if err := db.UpdateConnection(conn); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := history(t, db, conn.ID)
+	if len(entries) != 2 {
+		t.Fatalf("got %d history entries, want 2", len(entries))
+	}
+	update, add := entries[0], entries[1]
+	if add.Action != models.HistoryAdd || add.Before != nil {
+		t.Errorf("first entry = %s with before %v, want add without before", add.Action, add.Before)
+	}
+	if update.Action != models.HistoryUpdate || update.Before == nil || update.Before.Host != "web.example.com" {
+		t.Fatalf("latest entry = %+v, want update from web.example.com", update)
+	}
+	if got := changedFields(update.Changes); len(got) != 2 || got[0] != "host" || got[1] != "password" {
+		t.Errorf("update changed %v, want host and password", got)
+	}
+	for _, c := range update.Changes {
+		if c.Field == "password" && (c.Old == "web-pass" || c.New == "new-pass") {
+			t.Errorf("history exposes the password: %v", c)
+		}
+	}
+}
+
+func changedFields(changes []models.FieldChange) []string {
+	var fields []string
+	for _, c := range changes {
+		fields = append(fields, c.Field)
+	}
+	return fields
+}
+
+func TestRevertHistory(t *testing.T) {
+	db := openDB(t)
+	if err := db.AddConnection(testConn("web")); err != nil {
+		t.Fatal(err)
+	}
+	conn := mustFind(t, db, "web")
+	conn.Host = "10.0.0.1"
+	conn.Password = "new-pass"
+	if err := db.UpdateConnection(conn); err != nil {
+		t.Fatal(err)
+	}
+
+	// 撤销更新恢复修改前的字段和密码
+	if err := db.RevertHistory(history(t, db, conn.ID)[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	reverted := mustFind(t, db, "web")
+	if reverted.Host != "web.example.com" || decryptPassword(t, reverted) != "web-pass" {
+		t.Errorf("revert did not restore the connection: host %s", reverted.Host)
+	}
+	if reverted.Version <= conn.Version {
+		t.Errorf("revert kept version %d, stale copies would not conflict", reverted.Version)
+	}
+	entries := history(t, db, conn.ID)
+	if entries[0].Action != models.HistoryRevert {
+		t.Errorf("revert recorded as %s", entries[0].Action)
+	}
+
+	// 撤销删除恢复连接
+	if err := db.DeleteConnection(conn.ID); err != nil {
+		t.Fatal(err)
+	}
+	deleted, err := db.DeletedConnections()
+	if err != nil || len(deleted) != 1 || deleted[0].Name != "web" {
+		t.Fatalf("deleted connections = %v, err %v", deleted, err)
+	}
+	if err := db.RevertHistory(deleted[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if restored := mustFind(t, db, "web"); restored.Host != "web.example.com" {
+		t.Errorf("restored host = %s", restored.Host)
+	}
+	if deleted, _ := db.DeletedConnections(); len(deleted) != 0 {
+		t.Errorf("restored connection still listed as deleted: %v", deleted)
+	}
+
+	// 撤销新增即删除连接
+	entries = history(t, db, conn.ID)
+	if err := db.RevertHistory(entries[len(entries)-1].ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.GetConnection(conn.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("reverting the add left the connection: %v", err)
+	}
+}
+
+func TestIdentityHistory(t *testing.T) {
+	db := openDB(t)
+	identity := addIdentity(t, db, "ops")
+	conn := testConn("web")
+	conn.IdentityID = identity.ID
+	if err := db.AddConnection(conn); err != nil {
+		t.Fatal(err)
+	}
+
+	// 没有变化的修改不记录, 密码为空表示保留原密码
+	identity.Password = ""
+	if err := db.UpdateIdentity(identity); err != nil {
+		t.Fatal(err)
+	}
+	updated := identity
+	updated.Username = "admin"
+	updated.Password = "new-pass"
+	if err := db.UpdateIdentity(updated); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := db.IdentityHistory(identity.ID)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("identity history = %v, err %v", entries, err)
+	}
+	if got := changedFields(entries[0].Changes); len(got) != 2 || got[0] != "username" || got[1] != "password" {
+		t.Errorf("update changed %v, want username and password", got)
+	}
+
+	// 撤销后引用该身份的连接恢复原来的凭据
+	if err := db.RevertIdentityHistory(entries[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	reverted := mustFind(t, db, "web")
+	if err := ResolveConnection(db, &reverted); err != nil {
+		t.Fatal(err)
+	}
+	if reverted.Username != "deploy" || decryptPassword(t, reverted) != "ops-pass" {
+		t.Errorf("revert left username %s", reverted.Username)
+	}
+	if entries, _ := db.IdentityHistory(identity.ID); len(entries) != 2 || entries[0].Action != models.HistoryRevert {
+		t.Errorf("revert was not recorded: %v", entries)
+	}
+}
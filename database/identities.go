@@ -0,0 +1,286 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"tssh/models"
+)
+
+// ErrIdentityNotFound 身份不存在, 例如已被删除
+var ErrIdentityNotFound = errors.New("identity not found")
+
+const identityColumns = "id, name, username, auth_type, password, private_key, key_data, key_passphrase"
+
+func scanIdentity(s scanner) (models.Identity, error) {
+	var identity models.Identity
+	err := s.Scan(
+		&identity.ID,
+		&identity.Name,
+		&identity.Username,
+		&identity.AuthType,
+		&identity.Password,
+		&identity.PrivateKey,
+		&identity.KeyData,
+		&identity.KeyPassphrase,
+	)
+	return identity, err
+}
+
+func (db *DB) GetAllIdentities() ([]models.Identity, error) {
+	rows, err := db.Query("SELECT " + identityColumns + " FROM ssh_identities order by name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var identities []models.Identity
+	for rows.Next() {
+		identity, err := scanIdentity(rows)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+	return identities, nil
+}
+
+func (db *DB) GetIdentity(id int64) (models.Identity, error) {
+	return getIdentity(db, id)
+}
+
+func getIdentity(q queryer, id int64) (models.Identity, error) {
+	row := q.QueryRow("SELECT "+identityColumns+" FROM ssh_identities WHERE id = ?", id)
+	identity, err := scanIdentity(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Identity{}, ErrIdentityNotFound
+	}
+	if err != nil {
+		return models.Identity{}, err
+	}
+	return identity, nil
+}
+
+func (db *DB) AddIdentity(identity models.Identity) error {
+	if identity.AuthType == models.UsePass {
+		if identity.Password == "" {
+			return errors.New("password is required for password authentication")
+		}
+		var err error
+		identity.Password, err = models.EncryptString(identity.Password)
+		if err != nil {
+			return err
+		}
+	}
+	if err := encryptKeySecrets(&identity.KeyData, &identity.KeyPassphrase); err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO ssh_identities (name, username, auth_type, password, private_key, key_data, key_passphrase)
+	VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := db.Exec(query,
+		identity.Name,
+		identity.Username,
+		identity.AuthType,
+		identity.Password,
+		identity.PrivateKey,
+		identity.KeyData,
+		identity.KeyPassphrase,
+	)
+	return err
+}
+
+// UpdateIdentity 更新身份, 所有引用该身份的连接随之生效
+// 密码字段的处理方式与 UpdateConnection 一致
+func (db *DB) UpdateIdentity(identity models.Identity) error {
+	old, err := db.GetIdentity(identity.ID)
+	if err != nil {
+		return err
+	}
+	if identity.AuthType == models.UsePass && identity.Password != "" {
+		identity.Password, err = models.EncryptString(identity.Password)
+		if err != nil {
+			return err
+		}
+	} else {
+		if identity.AuthType == models.UsePass && old.Password == "" {
+			return errors.New("password is required for password authentication")
+		}
+		identity.Password = old.Password
+	}
+	if err := encryptKeySecrets(&identity.KeyData, &identity.KeyPassphrase); err != nil {
+		return err
+	}
+	if identity.KeyPassphrase == "" {
+		identity.KeyPassphrase = old.KeyPassphrase
+	}
+
+	return db.withTx(func(tx *sql.Tx) error {
+		if err := writeIdentity(tx, identity); err != nil {
+			return err
+		}
+		return recordIdentityHistory(tx, models.HistoryUpdate, old, identity)
+	})
+}
+
+// writeIdentity 按原样写入身份, 其中的密码类字段已经是密文
+func writeIdentity(q queryer, identity models.Identity) error {
+	query := `
+	UPDATE ssh_identities
+	SET name = ?, username = ?, auth_type = ?, password = ?, private_key = ?, key_data = ?, key_passphrase = ?
+	WHERE id = ?`
+
+	_, err := q.Exec(query,
+		identity.Name,
+		identity.Username,
+		identity.AuthType,
+		identity.Password,
+		identity.PrivateKey,
+		identity.KeyData,
+		identity.KeyPassphrase,
+		identity.ID,
+	)
+	return err
+}
+
+// DeleteIdentity 删除身份, 仍被连接 (包括回收站中的连接) 引用时拒绝删除
+func (db *DB) DeleteIdentity(id int64) error {
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM ssh_connections WHERE identity_id = ?", id).Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("identity is used by %d connection(s)", count)
+	}
+	if _, err := db.Exec("DELETE FROM connection_identities WHERE identity_id = ?", id); err != nil {
+		return err
+	}
+	if _, err := db.Exec("DELETE FROM identity_history WHERE identity_id = ?", id); err != nil {
+		return err
+	}
+	res, err := db.Exec("DELETE FROM ssh_identities WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrIdentityNotFound
+	}
+	return nil
+}
+
+// IdentityUsage 返回每个身份被引用的连接数量
+func (db *DB) IdentityUsage() (map[int64]int, error) {
+	rows, err := db.Query("SELECT identity_id, COUNT(*) FROM ssh_connections c WHERE identity_id != 0 AND " + notDeleted + " GROUP BY identity_id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usage := make(map[int64]int)
+	for rows.Next() {
+		var id int64
+		var count int
+		if err := rows.Scan(&id, &count); err != nil {
+			return nil, err
+		}
+		usage[id] = count
+	}
+	return usage, nil
+}
+
+// loadExtraIdentities 填充连接可选的其他登录身份
+func loadExtraIdentities(q queryer, connections []models.ConnInfo) error {
+	rows, err := q.Query("SELECT connection_id, identity_id FROM connection_identities ORDER BY identity_id")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	extras := make(map[int64][]int64)
+	for rows.Next() {
+		var connID, identityID int64
+		if err := rows.Scan(&connID, &identityID); err != nil {
+			return err
+		}
+		extras[connID] = append(extras[connID], identityID)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for i := range connections {
+		connections[i].ExtraIdentityIDs = extras[connections[i].ID]
+	}
+	return nil
+}
+
+// setExtraIdentities 替换连接可选的其他登录身份
+func setExtraIdentities(q queryer, connID int64, identityIDs []int64) error {
+	if _, err := q.Exec("DELETE FROM connection_identities WHERE connection_id = ?", connID); err != nil {
+		return err
+	}
+	for _, identityID := range identityIDs {
+		_, err := q.Exec("INSERT OR IGNORE INTO connection_identities (connection_id, identity_id) VALUES (?, ?)", connID, identityID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LinkMatchingConnections 将凭据与身份完全相同的连接改为引用该身份
+// 返回被关联的连接数量
+func (db *DB) LinkMatchingConnections(identityID int64) (int, error) {
+	identity, err := db.GetIdentity(identityID)
+	if err != nil {
+		return 0, err
+	}
+	want, err := credentialKey(identity.Username, identity.AuthType, identity.Password, identity.PrivateKey, identity.KeyData)
+	if err != nil {
+		return 0, err
+	}
+	connections, err := db.GetAllConnections()
+	if err != nil {
+		return 0, err
+	}
+	linked := 0
+	for _, conn := range connections {
+		if conn.IdentityID != 0 {
+			continue
+		}
+		got, err := credentialKey(conn.Username, conn.AuthType, conn.Password, conn.PrivateKey, conn.KeyData)
+		if err != nil || got != want {
+			continue
+		}
+		err = db.withTx(func(tx *sql.Tx) error {
+			if _, err := tx.Exec("UPDATE ssh_connections SET identity_id = ?, version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = ?", identityID, conn.ID); err != nil {
+				return err
+			}
+			after := conn
+			after.IdentityID = identityID
+			return recordHistory(tx, models.HistoryUpdate, conn.ID, &conn, &after)
+		})
+		if err != nil {
+			return linked, err
+		}
+		linked++
+	}
+	return linked, nil
+}
+
+// credentialKey 返回用于比较凭据是否相同的明文描述
+func credentialKey(username string, authType models.AuthType, password, privateKey, keyData string) (string, error) {
+	secret := privateKey
+	var err error
+	switch {
+	case authType == models.UsePass:
+		secret, err = models.DecryptString(password)
+	case keyData != "":
+		secret, err = models.DecryptString(keyData)
+	}
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s\x00%d\x00%s", username, authType, secret), nil
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"tssh/models"
+)
+
+func addIdentity(t *testing.T, db *DB, name string) models.Identity {
+	t.Helper()
+	err := db.AddIdentity(models.Identity{Name: name, Username: "deploy", AuthType: models.UsePass, Password: name + "-pass"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	identities, err := db.GetAllIdentities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, identity := range identities {
+		if identity.Name == name {
+			return identity
+		}
+	}
+	t.Fatalf("identity %s not found", name)
+	return models.Identity{}
+}
+
+func TestIdentityNotFound(t *testing.T) {
+	db := openDB(t)
+	identity := addIdentity(t, db, "ops")
+	if err := db.DeleteIdentity(identity.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.GetIdentity(identity.ID); !errors.Is(err, ErrIdentityNotFound) {
+		t.Errorf("GetIdentity after delete returned %v, want ErrIdentityNotFound", err)
+	}
+	if err := db.DeleteIdentity(identity.ID); !errors.Is(err, ErrIdentityNotFound) {
+		t.Errorf("deleting twice returned %v, want ErrIdentityNotFound", err)
+	}
+	if err := db.UpdateIdentity(identity); !errors.Is(err, ErrIdentityNotFound) {
+		t.Errorf("updating a deleted identity returned %v, want ErrIdentityNotFound", err)
+	}
+	conn := models.ConnInfo{Name: "web", IdentityID: identity.ID}
+	if err := ResolveConnection(db, &conn); !errors.Is(err, ErrIdentityNotFound) {
+		t.Errorf("resolving a deleted identity returned %v, want ErrIdentityNotFound", err)
+	}
+}
+
+func TestDeleteIdentityInUse(t *testing.T) {
+	db := openDB(t)
+	identity := addIdentity(t, db, "ops")
+	conn := models.ConnInfo{Name: "web", Host: "web.example.com", Port: 22, IdentityID: identity.ID}
+	if err := db.AddConnection(conn); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DeleteIdentity(identity.ID); err == nil || errors.Is(err, ErrIdentityNotFound) {
+		t.Errorf("deleting an identity in use returned %v", err)
+	}
+	if _, err := db.GetIdentity(identity.ID); err != nil {
+		t.Errorf("identity in use was deleted: %v", err)
+	}
+}
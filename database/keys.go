@@ -0,0 +1,57 @@
+package database
+
+import (
+	"tssh/models"
+)
+
+func (db *DB) GetAllKeys() ([]models.SSHKey, error) {
+	rows, err := db.Query("SELECT id, name, key_type, private_key, public_key, fingerprint FROM ssh_keys order by name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []models.SSHKey
+	for rows.Next() {
+		var key models.SSHKey
+		err := rows.Scan(
+			&key.ID,
+			&key.Name,
+			&key.KeyType,
+			&key.PrivateKey,
+			&key.PublicKey,
+			&key.Fingerprint,
+		)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// AddKey 保存密钥, PrivateKey 为明文, 存储前会加密
+func (db *DB) AddKey(key models.SSHKey) error {
+	privateKey, err := models.EncryptString(key.PrivateKey)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO ssh_keys (name, key_type, private_key, public_key, fingerprint)
+	VALUES (?, ?, ?, ?, ?)`
+
+	_, err = db.Exec(query,
+		key.Name,
+		key.KeyType,
+		privateKey,
+		key.PublicKey,
+		key.Fingerprint,
+	)
+	return err
+}
+
+func (db *DB) DeleteKey(id int64) error {
+	_, err := db.Exec("DELETE FROM ssh_keys WHERE id = ?", id)
+	return err
+}
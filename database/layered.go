@@ -0,0 +1,168 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"tssh/models"
+)
+
+// LayeredStore 在个人存储之上叠加只读的共享清单
+// 共享连接使用负数 ID 且不可修改, 个人存储支持时可覆盖共享连接的用户名和私钥
+// 新增的连接总是保存到个人存储中
+type LayeredStore struct {
+	personal    Store
+	inventories []*FileStore
+}
+
+// NewLayeredStore 创建叠加了共享清单的存储, 清单文件格式与 file 存储相同
+func NewLayeredStore(personal Store, inventoryPaths ...string) (*LayeredStore, error) {
+	s := &LayeredStore{personal: personal}
+	for _, path := range inventoryPaths {
+		// 与个人的 file 存储不同, 共享清单必须存在
+		if _, err := os.Stat(path); err != nil {
+			return nil, err
+		}
+		inventory, err := NewFileStore(path)
+		if err != nil {
+			return nil, err
+		}
+		s.inventories = append(s.inventories, inventory)
+	}
+	return s, nil
+}
+
+// Unwrap 返回个人存储, 用于获取个人存储支持的可选接口
+func (s *LayeredStore) Unwrap() Store {
+	return s.personal
+}
+
+// inventoryName 返回共享清单的名称, 即不含扩展名的文件名
+func inventoryName(inventory *FileStore) string {
+	base := filepath.Base(inventory.path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// shared 读取所有共享清单中的连接并应用个人覆盖设置
+// 共享连接的 ID 按清单和连接的顺序分配, 清单文件修改后可能变化
+func (s *LayeredStore) shared() ([]models.ConnInfo, error) {
+	overrides := make(map[[2]string]models.Override)
+	if store, ok := As[OverrideStore](s.personal); ok {
+		all, err := store.GetOverrides()
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range all {
+			overrides[[2]string{o.Source, o.Name}] = o
+		}
+	}
+
+	var connections []models.ConnInfo
+	var id int64
+	for _, inventory := range s.inventories {
+		all, err := inventory.GetAllConnections()
+		if err != nil {
+			return nil, err
+		}
+		source := inventoryName(inventory)
+		for _, conn := range all {
+			id--
+			conn.ID = id
+			conn.Source = source
+			// 共享清单无法引用个人存储中的身份
+			conn.IdentityID = 0
+			conn.ExtraIdentityIDs = nil
+			// 清单由他人维护, 不允许通过外部命令或 Vault 读取密码, 以免执行清单中的命令
+			conn.SecretBackend = models.SecretBackendLocal
+			conn.SecretRef = ""
+			if o, ok := overrides[[2]string{source, conn.Name}]; ok {
+				conn.ApplyOverride(o)
+			}
+			connections = append(connections, conn)
+		}
+	}
+	return connections, nil
+}
+
+func (s *LayeredStore) GetAllConnections() ([]models.ConnInfo, error) {
+	connections, err := s.personal.GetAllConnections()
+	if err != nil {
+		return nil, err
+	}
+	shared, err := s.shared()
+	if err != nil {
+		return nil, err
+	}
+	connections = append(connections, shared...)
+	sort.SliceStable(connections, func(i, j int) bool {
+		return connections[i].Name < connections[j].Name
+	})
+	return connections, nil
+}
+
+func (s *LayeredStore) GetConnection(id int64) (models.ConnInfo, error) {
+	if id >= 0 {
+		return s.personal.GetConnection(id)
+	}
+	shared, err := s.shared()
+	if err != nil {
+		return models.ConnInfo{}, err
+	}
+	for _, conn := range shared {
+		if conn.ID == id {
+			return conn, nil
+		}
+	}
+	return models.ConnInfo{}, ErrNotFound
+}
+
+func (s *LayeredStore) AddConnection(conn models.ConnInfo) error {
+	return s.personal.AddConnection(conn)
+}
+
+func (s *LayeredStore) UpdateConnection(conn models.ConnInfo) error {
+	if conn.ID < 0 {
+		return ErrReadOnly
+	}
+	return s.personal.UpdateConnection(conn)
+}
+
+func (s *LayeredStore) DeleteConnection(id int64) error {
+	if id < 0 {
+		return ErrReadOnly
+	}
+	return s.personal.DeleteConnection(id)
+}
+
+func (s *LayeredStore) SearchConnections(keyword string) ([]models.ConnInfo, error) {
+	connections, err := s.GetAllConnections()
+	if err != nil {
+		return nil, err
+	}
+	return filterConnections(connections, keyword), nil
+}
+
+// DataVersion 合并个人存储和所有共享清单的版本
+func (s *LayeredStore) DataVersion() (int64, error) {
+	var version int64
+	if w, ok := As[Watchable](s.personal); ok {
+		v, err := w.DataVersion()
+		if err != nil {
+			return 0, err
+		}
+		version = v
+	}
+	for _, inventory := range s.inventories {
+		v, err := inventory.DataVersion()
+		if err != nil {
+			return 0, err
+		}
+		version = version*31 + v
+	}
+	return version, nil
+}
+
+func (s *LayeredStore) Close() error {
+	return s.personal.Close()
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"tssh/models"
+)
+
+const testInventory = `connections:
+  - name: evil
+    host: evil.example.com
+    port: 22
+    username: root
+    auth_type: 1
+    secret_backend: command
+    secret_ref: touch /tmp/tssh-pwned
+  - name: vault
+    host: vault.example.com
+    port: 22
+    username: root
+    auth_type: 1
+    secret_backend: vault
+    secret_ref: secret/data/personal#password
+    identity_id: 1
+    extra_identity_ids: [2]
+`
+
+func TestLayeredStoreSanitizesSharedConnections(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "team.yaml")
+	if err := os.WriteFile(path, []byte(testInventory), 0600); err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewLayeredStore(NewMemoryStore(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	connections, err := store.GetAllConnections()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(connections) != 2 {
+		t.Fatalf("loaded %d shared connections, want 2", len(connections))
+	}
+	for _, conn := range connections {
+		if conn.Source != "team" || conn.ID >= 0 {
+			t.Errorf("%s: source %q id %d, want team with a negative id", conn.Name, conn.Source, conn.ID)
+		}
+		if conn.SecretBackend != models.SecretBackendLocal || conn.SecretRef != "" {
+			t.Errorf("%s: kept secret backend %q ref %q from the inventory", conn.Name, conn.SecretBackend, conn.SecretRef)
+		}
+		if conn.IdentityID != 0 || conn.ExtraIdentityIDs != nil {
+			t.Errorf("%s: kept identities %d %v from the inventory", conn.Name, conn.IdentityID, conn.ExtraIdentityIDs)
+		}
+		got, err := store.GetConnection(conn.ID)
+		if err != nil || got.SecretBackend != models.SecretBackendLocal || got.SecretRef != "" {
+			t.Errorf("%s: GetConnection returned backend %q ref %q, err %v", conn.Name, got.SecretBackend, got.SecretRef, err)
+		}
+		if err := store.UpdateConnection(conn); !errors.Is(err, ErrReadOnly) {
+			t.Errorf("%s: updating a shared connection returned %v, want ErrReadOnly", conn.Name, err)
+		}
+	}
+}
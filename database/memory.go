@@ -0,0 +1,127 @@
+package database
+
+import (
+	"sort"
+	"sync"
+	"tssh/models"
+)
+
+// connList 按 ID 管理连接列表, 供内存存储和文件存储共用
+type connList struct {
+	Connections []models.ConnInfo `json:"connections"`
+}
+
+func (l *connList) index(id int64) int {
+	for i, conn := range l.Connections {
+		if conn.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (l *connList) all() []models.ConnInfo {
+	connections := make([]models.ConnInfo, len(l.Connections))
+	copy(connections, l.Connections)
+	sort.SliceStable(connections, func(i, j int) bool {
+		return connections[i].Name < connections[j].Name
+	})
+	return connections
+}
+
+func (l *connList) get(id int64) (models.ConnInfo, error) {
+	i := l.index(id)
+	if i < 0 {
+		return models.ConnInfo{}, ErrNotFound
+	}
+	return l.Connections[i], nil
+}
+
+func (l *connList) add(conn models.ConnInfo) error {
+	if err := prepareNewConn(&conn); err != nil {
+		return err
+	}
+	var maxID int64
+	for _, c := range l.Connections {
+		maxID = max(maxID, c.ID)
+	}
+	conn.ID = maxID + 1
+	conn.Version = 1
+	l.Connections = append(l.Connections, conn)
+	return nil
+}
+
+func (l *connList) update(conn models.ConnInfo) error {
+	i := l.index(conn.ID)
+	if i < 0 {
+		return ErrNotFound
+	}
+	if l.Connections[i].Version != conn.Version {
+		return ErrConflict
+	}
+	if err := prepareUpdatedConn(&conn, l.Connections[i]); err != nil {
+		return err
+	}
+	conn.Version++
+	l.Connections[i] = conn
+	return nil
+}
+
+func (l *connList) delete(id int64) error {
+	i := l.index(id)
+	if i < 0 {
+		return ErrNotFound
+	}
+	l.Connections = append(l.Connections[:i], l.Connections[i+1:]...)
+	return nil
+}
+
+// MemoryStore 仅保存在内存中的连接存储, 用于测试或临时使用
+type MemoryStore struct {
+	mu   sync.Mutex
+	list connList
+}
+
+func NewMemoryStore(connections ...models.ConnInfo) *MemoryStore {
+	return &MemoryStore{list: connList{Connections: connections}}
+}
+
+func (s *MemoryStore) GetAllConnections() ([]models.ConnInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.list.all(), nil
+}
+
+func (s *MemoryStore) GetConnection(id int64) (models.ConnInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.list.get(id)
+}
+
+func (s *MemoryStore) AddConnection(conn models.ConnInfo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.list.add(conn)
+}
+
+func (s *MemoryStore) UpdateConnection(conn models.ConnInfo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.list.update(conn)
+}
+
+func (s *MemoryStore) DeleteConnection(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.list.delete(id)
+}
+
+func (s *MemoryStore) SearchConnections(keyword string) ([]models.ConnInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return filterConnections(s.list.all(), keyword), nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,38 @@
+package database
+
+import (
+	"tssh/models"
+)
+
+func (db *DB) GetOverrides() ([]models.Override, error) {
+	rows, err := db.Query("SELECT source, name, username, private_key FROM connection_overrides")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var overrides []models.Override
+	for rows.Next() {
+		var o models.Override
+		if err := rows.Scan(&o.Source, &o.Name, &o.Username, &o.PrivateKey); err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, o)
+	}
+	return overrides, rows.Err()
+}
+
+// SetOverride 保存覆盖设置, 所有字段为空时删除覆盖设置
+func (db *DB) SetOverride(o models.Override) error {
+	if o.Username == "" && o.PrivateKey == "" {
+		_, err := db.Exec("DELETE FROM connection_overrides WHERE source = ? AND name = ?", o.Source, o.Name)
+		return err
+	}
+	query := `
+	INSERT INTO connection_overrides (source, name, username, private_key)
+	VALUES (?, ?, ?, ?)
+	ON CONFLICT (source, name) DO UPDATE SET username = excluded.username, private_key = excluded.private_key`
+
+	_, err := db.Exec(query, o.Source, o.Name, o.Username, o.PrivateKey)
+	return err
+}
@@ -0,0 +1,118 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"tssh/models"
+)
+
+// prepareNewConn 校验新增连接的凭据并加密其中的明文密码
+func prepareNewConn(conn *models.ConnInfo) error {
+	if err := checkSecretRef(*conn); err != nil {
+		return err
+	}
+	if usesLocalPassword(*conn) {
+		if conn.Password == "" {
+			return errors.New("password is required for password authentication")
+		}
+		var err error
+		conn.Password, err = models.EncryptString(conn.Password)
+		if err != nil {
+			return err
+		}
+	} else {
+		// 不使用本地密码的连接不保存密码
+		conn.Password = ""
+	}
+	if err := encryptKeySecrets(&conn.KeyData, &conn.KeyPassphrase); err != nil {
+		return err
+	}
+	return encryptTOTPSecret(conn)
+}
+
+// prepareUpdatedConn 校验更新后连接的凭据并加密其中的明文密码
+// Password、KeyPassphrase 和 TOTPSecret 为空时保留 old 中的原值, KeyData 按传入值整体替换
+// 密码改由外部后端提供时清除本地保存的密码
+func prepareUpdatedConn(conn *models.ConnInfo, old models.ConnInfo) error {
+	if err := checkSecretRef(*conn); err != nil {
+		return err
+	}
+	var err error
+	if conn.AuthType == models.UsePass && conn.SecretBackend != models.SecretBackendLocal {
+		conn.Password = ""
+	} else if conn.AuthType == models.UsePass && conn.Password != "" {
+		conn.Password, err = models.EncryptString(conn.Password)
+		if err != nil {
+			return err
+		}
+	} else {
+		if usesLocalPassword(*conn) && conn.Password == "" && old.Password == "" {
+			return errors.New("password is required for password authentication")
+		}
+		conn.Password = old.Password
+	}
+	if err := encryptKeySecrets(&conn.KeyData, &conn.KeyPassphrase); err != nil {
+		return err
+	}
+	if conn.KeyPassphrase == "" {
+		conn.KeyPassphrase = old.KeyPassphrase
+	}
+	if err := encryptTOTPSecret(conn); err != nil {
+		return err
+	}
+	if conn.TOTPSecret == "" {
+		conn.TOTPSecret = old.TOTPSecret
+	}
+	return nil
+}
+
+// encryptKeySecrets 加密导入的私钥内容及其密码
+func encryptKeySecrets(keyData, keyPassphrase *string) error {
+	var err error
+	if *keyData != "" {
+		*keyData, err = models.EncryptString(*keyData)
+		if err != nil {
+			return err
+		}
+	}
+	if *keyPassphrase != "" {
+		*keyPassphrase, err = models.EncryptString(*keyPassphrase)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// usesLocalPassword 判断连接是否使用本地数据库中保存的密码
+func usesLocalPassword(conn models.ConnInfo) bool {
+	return conn.IdentityID == 0 && conn.AuthType == models.UsePass && conn.SecretBackend == models.SecretBackendLocal
+}
+
+// checkSecretRef 校验外部存储的密码引用
+func checkSecretRef(conn models.ConnInfo) error {
+	if conn.IdentityID != 0 || conn.AuthType != models.UsePass || conn.SecretBackend == models.SecretBackendLocal {
+		return nil
+	}
+	if !slices.Contains(models.SecretBackends, conn.SecretBackend) {
+		return fmt.Errorf("unknown secret backend: %s", conn.SecretBackend)
+	}
+	if conn.SecretRef == "" {
+		return fmt.Errorf("secret reference is required for the %s backend", conn.SecretBackend)
+	}
+	return nil
+}
+
+// encryptTOTPSecret 校验并加密 TOTP 密钥
+func encryptTOTPSecret(conn *models.ConnInfo) error {
+	if conn.TOTPSecret == "" {
+		return nil
+	}
+	if err := models.ValidateTOTPSecret(conn.TOTPSecret); err != nil {
+		return err
+	}
+	var err error
+	conn.TOTPSecret, err = models.EncryptString(conn.TOTPSecret)
+	return err
+}
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"testing"
+	"tssh/models"
+)
+
+func TestPrepareConnClearsPasswordForExternalBackends(t *testing.T) {
+	conn := models.ConnInfo{
+		AuthType:      models.UsePass,
+		Password:      "secret",
+		SecretBackend: models.SecretBackendCommand,
+		SecretRef:     "pass show web",
+	}
+	if err := prepareNewConn(&conn); err != nil {
+		t.Fatal(err)
+	}
+	if conn.Password != "" {
+		t.Errorf("new connection kept password %q for the command backend", conn.Password)
+	}
+
+	old := models.ConnInfo{AuthType: models.UsePass, Password: "old-ciphertext"}
+	updated := models.ConnInfo{
+		AuthType:      models.UsePass,
+		Password:      "new",
+		SecretBackend: models.SecretBackendVault,
+		SecretRef:     "secret/data/web",
+	}
+	if err := prepareUpdatedConn(&updated, old); err != nil {
+		t.Fatal(err)
+	}
+	if updated.Password != "" {
+		t.Errorf("switching to vault kept password %q", updated.Password)
+	}
+
+	local := models.ConnInfo{AuthType: models.UsePass}
+	if err := prepareUpdatedConn(&local, old); err != nil {
+		t.Fatal(err)
+	}
+	if local.Password != old.Password {
+		t.Errorf("empty password replaced the stored one: %q", local.Password)
+	}
+}
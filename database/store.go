@@ -0,0 +1,144 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"tssh/models"
+)
+
+// Store 连接信息的存储
+// 新增和更新时传入明文密码, 由存储负责加密, 读取时返回密文
+type Store interface {
+	GetAllConnections() ([]models.ConnInfo, error)
+	GetConnection(id int64) (models.ConnInfo, error)
+	AddConnection(conn models.ConnInfo) error
+	UpdateConnection(conn models.ConnInfo) error
+	DeleteConnection(id int64) error
+	SearchConnections(keyword string) ([]models.ConnInfo, error)
+	Close() error
+}
+
+// IdentityStore 支持共享身份的存储
+type IdentityStore interface {
+	GetAllIdentities() ([]models.Identity, error)
+	GetIdentity(id int64) (models.Identity, error)
+	AddIdentity(identity models.Identity) error
+	UpdateIdentity(identity models.Identity) error
+	DeleteIdentity(id int64) error
+	IdentityUsage() (map[int64]int, error)
+	LinkMatchingConnections(identityID int64) (int, error)
+}
+
+// KeyStore 支持保存 SSH 密钥的存储
+type KeyStore interface {
+	GetAllKeys() ([]models.SSHKey, error)
+	AddKey(key models.SSHKey) error
+	DeleteKey(id int64) error
+}
+
+// OverrideStore 支持保存共享连接个人覆盖设置的存储
+type OverrideStore interface {
+	GetOverrides() ([]models.Override, error)
+	SetOverride(o models.Override) error
+}
+
+// HistoryStore 支持记录和撤销连接及共享身份修改的存储
+type HistoryStore interface {
+	ConnectionHistory(connID int64) ([]models.HistoryEntry, error)
+	DeletedConnections() ([]models.HistoryEntry, error)
+	RevertHistory(entryID int64) error
+	IdentityHistory(identityID int64) ([]models.HistoryEntry, error)
+	RevertIdentityHistory(entryID int64) error
+}
+
+// TrashStore 删除连接时先移入回收站的存储
+type TrashStore interface {
+	TrashedConnections() ([]models.TrashedConn, error)
+	RestoreConnection(id int64) error
+	// PurgeConnection 彻底删除回收站中的连接
+	PurgeConnection(id int64) error
+	// PurgeTrash 彻底删除在回收站中超过 olderThan 的连接, 返回删除的数量
+	PurgeTrash(olderThan time.Duration) (int, error)
+}
+
+// UsageStore 可以记录连接使用时间的存储
+type UsageStore interface {
+	// MarkUsed 将连接的最近使用时间设为当前时间, 不视为对连接的修改
+	MarkUsed(id int64) error
+}
+
+// Watchable 可以感知其他 tssh 实例修改的存储
+type Watchable interface {
+	// DataVersion 返回存储数据的版本, 其他实例修改数据后版本发生变化
+	DataVersion() (int64, error)
+}
+
+// As 返回存储实现的可选接口, 存储包装了个人存储时检查被包装的存储
+func As[T any](store Store) (T, bool) {
+	for store != nil {
+		if s, ok := store.(T); ok {
+			return s, true
+		}
+		w, ok := store.(interface{ Unwrap() Store })
+		if !ok {
+			break
+		}
+		store = w.Unwrap()
+	}
+	var zero T
+	return zero, false
+}
+
+// 存储类型
+const (
+	StoreSQLite = "sqlite"
+	StoreFile   = "file"
+	StoreMemory = "memory"
+)
+
+var (
+	ErrNotFound = errors.New("connection not found")
+	ErrReadOnly = errors.New("shared connection is read-only")
+)
+
+// Open 打开指定类型的存储, path 为 SQLite 数据库或清单文件的路径
+func Open(kind string, path string) (Store, error) {
+	switch kind {
+	case "", StoreSQLite:
+		return NewDB(path)
+	case StoreFile:
+		return NewFileStore(path)
+	case StoreMemory:
+		return NewMemoryStore(), nil
+	}
+	return nil, fmt.Errorf("unknown store type: %s", kind)
+}
+
+// ResolveConnection 将连接引用的身份凭据填充到连接中
+func ResolveConnection(store Store, conn *models.ConnInfo) error {
+	if conn.IdentityID == 0 {
+		return nil
+	}
+	identities, ok := As[IdentityStore](store)
+	if !ok {
+		return errors.New("store does not support identities")
+	}
+	identity, err := identities.GetIdentity(conn.IdentityID)
+	if err != nil {
+		return fmt.Errorf("failed to load identity: %w", err)
+	}
+	conn.ApplyIdentity(identity)
+	return nil
+}
+
+// filterConnections 返回匹配关键字的连接
+func filterConnections(connections []models.ConnInfo, keyword string) []models.ConnInfo {
+	var matched []models.ConnInfo
+	for _, conn := range connections {
+		if conn.Matches(keyword) {
+			matched = append(matched, conn)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,275 @@
+package database
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"tssh/models"
+)
+
+// storeKinds 需要通过一致性测试的存储实现
+var storeKinds = []struct {
+	name string
+	open func(t *testing.T) Store
+}{
+	{"sqlite", func(t *testing.T) Store { return openStore(t, StoreSQLite, "tssh.db") }},
+	{"json", func(t *testing.T) Store { return openStore(t, StoreFile, "tssh.json") }},
+	{"yaml", func(t *testing.T) Store { return openStore(t, StoreFile, "tssh.yaml") }},
+	{"memory", func(t *testing.T) Store { return openStore(t, StoreMemory, "") }},
+}
+
+func openStore(t *testing.T, kind, name string) Store {
+	t.Helper()
+	store, err := Open(kind, filepath.Join(t.TempDir(), name))
+	if err != nil {
+		t.Fatalf("open %s store: %v", kind, err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// forEachStore 对每种存储实现运行 fn
+func forEachStore(t *testing.T, fn func(t *testing.T, store Store)) {
+	for _, kind := range storeKinds {
+		t.Run(kind.name, func(t *testing.T) {
+			fn(t, kind.open(t))
+		})
+	}
+}
+
+func testConn(name string) models.ConnInfo {
+	return models.ConnInfo{
+		Name:     name,
+		Host:     name + ".example.com",
+		Port:     22,
+		Username: "root",
+		AuthType: models.UsePass,
+		Password: name + "-pass",
+		Notes:    "notes of " + name,
+		Meta:     map[string]string{"env": "prod"},
+	}
+}
+
+// mustFind 按名称查找连接
+func mustFind(t *testing.T, store Store, name string) models.ConnInfo {
+	t.Helper()
+	connections, err := store.GetAllConnections()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, conn := range connections {
+		if conn.Name == name {
+			return conn
+		}
+	}
+	t.Fatalf("connection %s not found", name)
+	return models.ConnInfo{}
+}
+
+func decryptPassword(t *testing.T, conn models.ConnInfo) string {
+	t.Helper()
+	pass, err := models.DecryptString(conn.Password)
+	if err != nil {
+		t.Fatalf("decrypt password of %s: %v", conn.Name, err)
+	}
+	return pass
+}
+
+func TestStoreAddAndGet(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		connections, err := store.GetAllConnections()
+		if err != nil || len(connections) != 0 {
+			t.Fatalf("new store has %d connections, err %v", len(connections), err)
+		}
+		for _, name := range []string{"web", "db"} {
+			if err := store.AddConnection(testConn(name)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		connections, err = store.GetAllConnections()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(connections) != 2 || connections[0].Name != "db" || connections[1].Name != "web" {
+			t.Fatalf("connections are not sorted by name: %+v", connections)
+		}
+		if connections[0].ID == connections[1].ID {
+			t.Fatalf("connections share id %d", connections[0].ID)
+		}
+
+		web, err := store.GetConnection(connections[1].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if web.Version != 1 {
+			t.Errorf("new connection has version %d, want 1", web.Version)
+		}
+		if web.Password == "web-pass" {
+			t.Error("password is stored in plaintext")
+		}
+		if got := decryptPassword(t, web); got != "web-pass" {
+			t.Errorf("password = %q, want web-pass", got)
+		}
+		if web.Host != "web.example.com" || web.Notes != "notes of web" || web.Meta["env"] != "prod" {
+			t.Errorf("connection fields not preserved: %+v", web)
+		}
+	})
+}
+
+func TestStoreValidatesCredentials(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		conn := testConn("web")
+		conn.Password = ""
+		if err := store.AddConnection(conn); err == nil {
+			t.Error("added a password connection without password")
+		}
+		conn = testConn("web")
+		conn.SecretBackend = models.SecretBackendVault
+		if err := store.AddConnection(conn); err == nil {
+			t.Error("added a vault connection without secret reference")
+		}
+	})
+}
+
+func TestStoreUpdate(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		if err := store.AddConnection(testConn("web")); err != nil {
+			t.Fatal(err)
+		}
+		conn := mustFind(t, store, "web")
+		conn.Host = "10.0.0.1"
+		conn.Password = ""
+		if err := store.UpdateConnection(conn); err != nil {
+			t.Fatal(err)
+		}
+		updated := mustFind(t, store, "web")
+		if updated.Host != "10.0.0.1" || updated.Version != conn.Version+1 {
+			t.Errorf("update not applied: host %s version %d", updated.Host, updated.Version)
+		}
+		if got := decryptPassword(t, updated); got != "web-pass" {
+			t.Errorf("empty password replaced the stored one: %q", got)
+		}
+
+		updated.Password = "new-pass"
+		if err := store.UpdateConnection(updated); err != nil {
+			t.Fatal(err)
+		}
+		if got := decryptPassword(t, mustFind(t, store, "web")); got != "new-pass" {
+			t.Errorf("password = %q, want new-pass", got)
+		}
+
+		missing := testConn("missing")
+		missing.ID = 999
+		if err := store.UpdateConnection(missing); !errors.Is(err, ErrNotFound) {
+			t.Errorf("updating a missing connection returned %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestStoreDeleteAndSearch(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		web := testConn("web")
+		web.Meta = map[string]string{"env": "staging"}
+		for _, conn := range []models.ConnInfo{web, testConn("db")} {
+			if err := store.AddConnection(conn); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for keyword, want := range map[string]int{"web": 1, "example.com": 2, "env=prod": 1, "nothing": 0} {
+			found, err := store.SearchConnections(keyword)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(found) != want {
+				t.Errorf("search %q found %d connections, want %d", keyword, len(found), want)
+			}
+		}
+
+		id := mustFind(t, store, "web").ID
+		if err := store.DeleteConnection(id); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.GetConnection(id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("getting a deleted connection returned %v, want ErrNotFound", err)
+		}
+		if err := store.DeleteConnection(id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("deleting twice returned %v, want ErrNotFound", err)
+		}
+		connections, err := store.GetAllConnections()
+		if err != nil || len(connections) != 1 {
+			t.Errorf("%d connections left after delete, err %v", len(connections), err)
+		}
+	})
+}
+
+func TestFileStorePersists(t *testing.T) {
+	for _, name := range []string{"tssh.json", "tssh.yaml"} {
+		path := filepath.Join(t.TempDir(), name)
+		store, err := NewFileStore(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.AddConnection(testConn("web")); err != nil {
+			t.Fatal(err)
+		}
+		reopened, err := NewFileStore(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn := mustFind(t, reopened, "web")
+		if conn.Host != "web.example.com" || decryptPassword(t, conn) != "web-pass" {
+			t.Errorf("%s: connection not persisted: %+v", name, conn)
+		}
+	}
+}
+
+func TestStoreStaleVersionConflict(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		if err := store.AddConnection(testConn("web")); err != nil {
+			t.Fatal(err)
+		}
+		stale := mustFind(t, store, "web")
+		fresh := stale
+		fresh.Host = "10.0.0.1"
+		if err := store.UpdateConnection(fresh); err != nil {
+			t.Fatal(err)
+		}
+		stale.Host = "10.0.0.2"
+		if err := store.UpdateConnection(stale); !errors.Is(err, ErrConflict) {
+			t.Fatalf("updating a stale connection returned %v, want ErrConflict", err)
+		}
+		if host := mustFind(t, store, "web").Host; host != "10.0.0.1" {
+			t.Errorf("stale update overwrote host: %s", host)
+		}
+	})
+}
+
+// 两个 tssh 实例打开同一个数据库或清单文件时, 后保存的实例应得到 ErrConflict
+func TestStoreConflictBetweenInstances(t *testing.T) {
+	for _, tt := range []struct{ kind, name string }{{StoreSQLite, "tssh.db"}, {StoreFile, "tssh.yaml"}} {
+		path := filepath.Join(t.TempDir(), tt.name)
+		first, err := Open(tt.kind, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer first.Close()
+		second, err := Open(tt.kind, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer second.Close()
+
+		if err := first.AddConnection(testConn("web")); err != nil {
+			t.Fatal(err)
+		}
+		mine, theirs := mustFind(t, first, "web"), mustFind(t, second, "web")
+		theirs.Host = "10.0.0.1"
+		if err := second.UpdateConnection(theirs); err != nil {
+			t.Fatal(err)
+		}
+		mine.Host = "10.0.0.2"
+		if err := first.UpdateConnection(mine); !errors.Is(err, ErrConflict) {
+			t.Errorf("%s: concurrent update returned %v, want ErrConflict", tt.kind, err)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+	"tssh/models"
+)
+
+// trashConn 将连接移入回收站, 保留其关联的身份以便恢复
+func trashConn(q queryer, id int64) error {
+	_, err := q.Exec("UPDATE ssh_connections SET deleted_at = ?, version = version + 1 WHERE id = ?",
+		time.Now().UTC().Format(time.RFC3339), id)
+	return err
+}
+
+// extraScanner 在 scanConn 的列之后读取额外的列
+type extraScanner struct {
+	scanner
+	extra []any
+}
+
+func (s extraScanner) Scan(dest ...any) error {
+	return s.scanner.Scan(append(dest, s.extra...)...)
+}
+
+// TrashedConnections 返回回收站中的连接, 最近删除的在前
+func (db *DB) TrashedConnections() ([]models.TrashedConn, error) {
+	rows, err := db.Query("SELECT " + connColumns + ", c.deleted_at FROM " + connFrom + " WHERE c.deleted_at != '' ORDER BY c.deleted_at DESC, c.name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var conns []models.ConnInfo
+	var deletedAt []string
+	for rows.Next() {
+		var at string
+		conn, err := scanConn(extraScanner{rows, []any{&at}})
+		if err != nil {
+			return nil, err
+		}
+		conns = append(conns, conn)
+		deletedAt = append(deletedAt, at)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := loadExtraIdentities(db, conns); err != nil {
+		return nil, err
+	}
+	trashed := make([]models.TrashedConn, len(conns))
+	for i, conn := range conns {
+		trashed[i].ConnInfo = conn
+		trashed[i].DeletedAt, _ = time.Parse(time.RFC3339, deletedAt[i])
+	}
+	return trashed, nil
+}
+
+// RestoreConnection 将连接移出回收站
+func (db *DB) RestoreConnection(id int64) error {
+	return db.withTx(func(tx *sql.Tx) error {
+		res, err := tx.Exec("UPDATE ssh_connections SET deleted_at = '', version = version + 1 WHERE id = ? AND deleted_at != ''", id)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrNotFound
+		}
+		conn, err := getConnection(tx, id)
+		if err != nil {
+			return err
+		}
+		return recordHistory(tx, models.HistoryRestore, id, nil, &conn)
+	})
+}
+
+// PurgeConnection 彻底删除回收站中的连接, 修改记录仍然保留
+func (db *DB) PurgeConnection(id int64) error {
+	return db.withTx(func(tx *sql.Tx) error {
+		res, err := tx.Exec("DELETE FROM ssh_connections WHERE id = ? AND deleted_at != ''", id)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrNotFound
+		}
+		return setExtraIdentities(tx, id, nil)
+	})
+}
+
+// PurgeTrash 彻底删除在回收站中超过 olderThan 的连接, 返回删除的数量
+func (db *DB) PurgeTrash(olderThan time.Duration) (int, error) {
+	var count int
+	err := db.withTx(func(tx *sql.Tx) error {
+		cutoff := time.Now().Add(-olderThan).UTC().Format(time.RFC3339)
+		res, err := tx.Exec("DELETE FROM ssh_connections WHERE deleted_at != '' AND deleted_at < ?", cutoff)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		count = int(n)
+		_, err = tx.Exec("DELETE FROM connection_identities WHERE connection_id NOT IN (SELECT id FROM ssh_connections)")
+		return err
+	})
+	return count, err
+}
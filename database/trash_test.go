@@ -0,0 +1,84 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTrashRestore(t *testing.T) {
+	db := openDB(t)
+	if err := db.AddConnection(testConn("web")); err != nil {
+		t.Fatal(err)
+	}
+	stale := mustFind(t, db, "web")
+	if err := db.DeleteConnection(stale.ID); err != nil {
+		t.Fatal(err)
+	}
+	trashed, err := db.TrashedConnections()
+	if err != nil || len(trashed) != 1 || trashed[0].ID != stale.ID || trashed[0].DeletedAt.IsZero() {
+		t.Fatalf("trashed connections = %+v, err %v", trashed, err)
+	}
+
+	if err := db.RestoreConnection(stale.ID); err != nil {
+		t.Fatal(err)
+	}
+	restored := mustFind(t, db, "web")
+	if restored.ID != stale.ID || decryptPassword(t, restored) != "web-pass" {
+		t.Errorf("restored connection %+v", restored)
+	}
+	if trashed, _ := db.TrashedConnections(); len(trashed) != 0 {
+		t.Errorf("restored connection still in trash: %+v", trashed)
+	}
+	if err := db.RestoreConnection(stale.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("restoring a connection not in trash returned %v, want ErrNotFound", err)
+	}
+	// 删除和恢复都会更新版本, 之前读取的连接不能覆盖恢复后的连接
+	if err := db.UpdateConnection(stale); !errors.Is(err, ErrConflict) {
+		t.Errorf("updating a copy from before the delete returned %v, want ErrConflict", err)
+	}
+}
+
+func TestTrashPurge(t *testing.T) {
+	db := openDB(t)
+	for _, name := range []string{"old", "recent", "kept"} {
+		if err := db.AddConnection(testConn(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old, recent, kept := mustFind(t, db, "old"), mustFind(t, db, "recent"), mustFind(t, db, "kept")
+	for _, id := range []int64{old.ID, recent.ID} {
+		if err := db.DeleteConnection(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deletedAt := time.Now().Add(-40 * 24 * time.Hour).UTC().Format(time.RFC3339)
+	if _, err := db.Exec("UPDATE ssh_connections SET deleted_at = ? WHERE id = ?", deletedAt, old.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := db.PurgeTrash(30 * 24 * time.Hour)
+	if err != nil || n != 1 {
+		t.Fatalf("PurgeTrash removed %d connections, err %v, want 1", n, err)
+	}
+	trashed, err := db.TrashedConnections()
+	if err != nil || len(trashed) != 1 || trashed[0].ID != recent.ID {
+		t.Fatalf("trash after purge = %+v, err %v, want only recent", trashed, err)
+	}
+	if err := db.RestoreConnection(old.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("restoring a purged connection returned %v, want ErrNotFound", err)
+	}
+
+	if err := db.PurgeConnection(kept.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("purging a connection not in trash returned %v, want ErrNotFound", err)
+	}
+	if err := db.PurgeConnection(recent.ID); err != nil {
+		t.Fatal(err)
+	}
+	if trashed, _ := db.TrashedConnections(); len(trashed) != 0 {
+		t.Errorf("trash not empty after purge: %+v", trashed)
+	}
+	if mustFind(t, db, "kept").ID != kept.ID {
+		t.Error("purge removed a live connection")
+	}
+}
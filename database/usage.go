@@ -0,0 +1,16 @@
+package database
+
+import "time"
+
+// MarkUsed 记录连接的最近使用时间
+// 不递增 version, 以免与其他实例中正在编辑的表单产生冲突
+func (db *DB) MarkUsed(id int64) error {
+	res, err := db.Exec("UPDATE ssh_connections SET last_used = ? WHERE id = ? AND deleted_at = ''", time.Now().UTC().Format(time.RFC3339), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
-	"path/filepath"
+	"strings"
+	"time"
+	"tssh/config"
 	"tssh/database"
 	"tssh/ssh"
 	"tssh/ui"
@@ -12,44 +15,162 @@ import (
 )
 
 func main() {
-	// 获取用户主目录
-	homeDir, err := os.UserHomeDir()
+	// 作为 ssh 的 SSH_ASKPASS 程序运行
+	if ssh.IsAskpass() {
+		os.Exit(ssh.RunAskpass(os.Args[1:]))
+	}
+
+	configPath := flag.String("config", "", "config file, default $XDG_CONFIG_HOME/tssh/config.yaml")
+	dataDir := flag.String("data-dir", "", "directory of the database and other data files")
+	storeKind := flag.String("store", "", "connection store: sqlite, file or memory")
+	storePath := flag.String("db", "", "path of the sqlite database or inventory file")
+	flag.StringVar(storePath, "store-path", "", "alias of -db")
+	var inventories pathList
+	flag.Var(&inventories, "inventory", "read-only shared inventory file, can be repeated")
+	trashRetention := flag.Duration("trash-retention", 0, "purge deleted connections older than this, 0 keeps them forever")
+	flag.Parse()
+
+	// 读取配置, 命令行参数优先于环境变量和配置文件
+	cfg, err := config.Load(*configPath)
 	if err != nil {
-		fmt.Printf("Error getting home directory: %v\n", err)
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "data-dir":
+			cfg.DataDir = *dataDir
+		case "store":
+			cfg.Store.Kind = *storeKind
+		case "db", "store-path":
+			cfg.Store.Path = *storePath
+		case "inventory":
+			cfg.Store.Inventories = append(cfg.Store.Inventories, inventories...)
+		case "trash-retention":
+			cfg.Store.TrashRetention = config.Duration(*trashRetention)
+		}
+	})
+	if err := cfg.Validate(); err != nil {
+		fmt.Printf("Invalid config %s: %v\n", cfg.Path(), err)
+		os.Exit(1)
+	}
+
+	if err := ui.SetKeyBindings(cfg.Keys); err != nil {
+		fmt.Printf("Invalid key bindings in %s: %v\n", cfg.Path(), err)
+		os.Exit(1)
+	}
+	if err := ui.CheckColumns(cfg.UI.Columns, cfg.UI.Sort); err != nil {
+		fmt.Printf("Invalid columns in %s: %v\n", cfg.Path(), err)
 		os.Exit(1)
 	}
 
-	// 创建配置目录
-	configDir := filepath.Join(homeDir, ".xssh")
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		fmt.Printf("Error creating config directory: %v\n", err)
+	// 创建数据目录
+	if err := cfg.PrepareDataDir(); err != nil {
+		fmt.Printf("Error creating data directory: %v\n", err)
 		os.Exit(1)
 	}
 
-	// 初始化数据库
-	dbPath := filepath.Join(configDir, "connections.db")
-	db, err := database.NewDB(dbPath)
+	// 初始化存储
+	db, err := database.Open(cfg.Store.Kind, cfg.Store.Path)
 	if err != nil {
 		fmt.Printf("Error initializing database: %v\n", err)
 		os.Exit(1)
 	}
 	defer db.Close()
 
-	// 获取所有连接
-	connections, err := db.GetAllConnections()
-	if err != nil {
-		fmt.Printf("Error getting connections: %v\n", err)
-		os.Exit(1)
+	// 清理回收站中过期的连接
+	if trash, ok := database.As[database.TrashStore](db); ok && cfg.Store.TrashRetention > 0 {
+		if _, err := trash.PurgeTrash(time.Duration(cfg.Store.TrashRetention)); err != nil {
+			fmt.Printf("Error purging trash: %v\n", err)
+			os.Exit(1)
+		}
 	}
 
-	// 启动 TUI
-	p := tea.NewProgram(ui.InitialModel(connections, db))
-	m, err := p.Run()
-	if err != nil {
-		fmt.Printf("Error running program: %v\n", err)
+	// 叠加只读的共享清单
+	if len(cfg.Store.Inventories) > 0 {
+		db, err = database.NewLayeredStore(db, cfg.Store.Inventories...)
+		if err != nil {
+			fmt.Printf("Error loading inventory: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// 执行子命令
+	if flag.NArg() > 0 {
+		run, ok := commands[flag.Arg(0)]
+		if !ok {
+			fmt.Printf("Unknown command: %s\n", flag.Arg(0))
+			os.Exit(2)
+		}
+		if err := run(db, flag.Args()[1:]); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			db.Close()
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := ui.SetTheme(cfg.UI.Theme, cfg.ThemeDir()); err != nil {
+		fmt.Printf("Error loading theme: %v\n", err)
 		os.Exit(1)
 	}
-	if mm, ok := m.(*ui.MainModel); ok && mm.WillConn != nil {
-		ssh.Connect(mm.WillConn)
+
+	for {
+		// 获取所有连接
+		connections, err := db.GetAllConnections()
+		if err != nil {
+			fmt.Printf("Error getting connections: %v\n", err)
+			os.Exit(1)
+		}
+
+		// 启动 TUI
+		var opts []tea.ProgramOption
+		if cfg.UI.Mouse {
+			// 全屏模式下界面从终端的第一行开始显示, 鼠标的坐标才能对应到界面上
+			opts = append(opts, tea.WithAltScreen(), tea.WithMouseCellMotion())
+		}
+		p := tea.NewProgram(ui.InitialModel(connections, db, cfg), opts...)
+		stopWatch := ui.Watch(p, 2*time.Second)
+		m, err := p.Run()
+		stopWatch()
+		if err != nil {
+			fmt.Printf("Error running program: %v\n", err)
+			os.Exit(1)
+		}
+		mm, ok := m.(*ui.MainModel)
+		if !ok {
+			return
+		}
+		if err := mm.SaveSort(); err != nil {
+			fmt.Printf("Failed to save config: %v\n", err)
+		}
+		if mm.WillConn == nil {
+			return
+		}
+		if err := database.ResolveConnection(db, mm.WillConn.Context); err != nil {
+			fmt.Printf("Error resolving connection: %v\n", err)
+			os.Exit(1)
+		}
+		if usage, ok := database.As[database.UsageStore](db); ok && mm.WillConn.Context.Source == "" {
+			// 使用时间只用于显示, 记录失败不影响连接
+			usage.MarkUsed(mm.WillConn.Context.ID)
+		}
+		ssh.Connect(mm.WillConn, cfg.Connect)
+		// 连接断开后回到连接列表
+		if !cfg.Connect.ReturnToList {
+			return
+		}
 	}
 }
+
+// pathList 可重复指定的路径参数
+type pathList []string
+
+func (l *pathList) String() string {
+	return strings.Join(*l, string(os.PathListSeparator))
+}
+
+func (l *pathList) Set(path string) error {
+	*l = append(*l, path)
+	return nil
+}
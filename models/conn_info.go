@@ -1,5 +1,13 @@
 package models
 
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
 type AuthType int
 type RunCommand string
 
@@ -22,8 +30,94 @@ type ConnInfo struct {
 	Name       string   `json:"name" validate:"required"`
 	Host       string   `json:"host" validate:"required"`
 	Port       int      `json:"port" validate:"required"`
-	Username   string   `json:"username" validate:"required"`
-	AuthType   AuthType `json:"auth_type" validate:"required"`
+	Username   string   `json:"username" validate:"required_without=IdentityID"`
+	AuthType   AuthType `json:"auth_type" validate:"required_without=IdentityID"`
 	Password   string   `json:"password,omitempty"`
 	PrivateKey string   `json:"private_key,omitempty"`
+	// KeyData 导入到 tssh 中的私钥内容(加密存储), 不为空时优先于 PrivateKey 路径
+	KeyData string `json:"key_data,omitempty"`
+	// KeyPassphrase 私钥的密码(加密存储)
+	KeyPassphrase string `json:"key_passphrase,omitempty"`
+	// IdentityID 引用的共享身份, 不为 0 时使用身份的用户名和凭据
+	IdentityID int64 `json:"identity_id,omitempty"`
+	// ExtraIdentityIDs 该主机可选的其他登录身份, 连接时可从中选择
+	ExtraIdentityIDs []int64 `json:"extra_identity_ids,omitempty"`
+	// TOTPSecret 用于 keyboard-interactive 认证的 TOTP 密钥(加密存储)
+	TOTPSecret string `json:"totp_secret,omitempty"`
+	// SecretBackend 密码的存储位置, 为空时密码加密保存在 Password 中
+	SecretBackend string `json:"secret_backend,omitempty"`
+	// SecretRef 外部存储中密码的引用, 如外部命令或 Vault 路径
+	SecretRef string `json:"secret_ref,omitempty"`
+	// Notes Markdown 格式的备注
+	Notes string `json:"notes,omitempty"`
+	// Meta 自定义的键值信息, 如负责人、环境、工单链接
+	Meta map[string]string `json:"meta,omitempty"`
+	// Version 每次更新后递增, 用于检测其他 tssh 实例的并发修改
+	Version int64 `json:"version,omitempty"`
+	// LastUsed 最近一次连接的时间, 零值表示从未连接过, 不导出
+	LastUsed time.Time `json:"-"`
+	// Source 连接来源, 为空表示个人连接, 否则为只读共享清单的名称
+	Source string `json:"-"`
+}
+
+// Matches 判断连接的名称、主机、用户名、备注或自定义信息是否包含关键字(忽略大小写)
+// 自定义信息按 "键=值" 匹配, 因此可以用 env=prod 精确筛选
+func (c *ConnInfo) Matches(keyword string) bool {
+	if keyword == "" {
+		return true
+	}
+	keyword = strings.ToLower(keyword)
+	if strings.Contains(strings.ToLower(c.Name), keyword) ||
+		strings.Contains(strings.ToLower(c.Host), keyword) ||
+		strings.Contains(strings.ToLower(c.Username), keyword) ||
+		strings.Contains(strings.ToLower(c.Notes), keyword) {
+		return true
+	}
+	for k, v := range c.Meta {
+		if strings.Contains(strings.ToLower(k+"="+v), keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// MetaKeys 返回排序后的自定义信息的键
+func (c *ConnInfo) MetaKeys() []string {
+	return slices.Sorted(maps.Keys(c.Meta))
+}
+
+// FormatMeta 将自定义信息格式化为每行一个 "键: 值"
+func FormatMeta(meta map[string]string) string {
+	var b strings.Builder
+	for _, k := range slices.Sorted(maps.Keys(meta)) {
+		b.WriteString(k + ": " + meta[k] + "\n")
+	}
+	return b.String()
+}
+
+// ParseMeta 解析每行一个 "键: 值" 或 "键=值" 的自定义信息, 忽略空行
+func ParseMeta(text string) (map[string]string, error) {
+	meta := make(map[string]string)
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		sep := strings.IndexAny(line, ":=")
+		if sep <= 0 {
+			return nil, fmt.Errorf("metadata line %d: expected key: value", i+1)
+		}
+		k, v := strings.TrimSpace(line[:sep]), strings.TrimSpace(line[sep+1:])
+		if k == "" {
+			return nil, fmt.Errorf("metadata line %d: empty key", i+1)
+		}
+		if _, ok := meta[k]; ok {
+			return nil, fmt.Errorf("metadata line %d: duplicate key %q", i+1, k)
+		}
+		meta[k] = v
+	}
+	if len(meta) == 0 {
+		return nil, nil
+	}
+	return meta, nil
 }
@@ -0,0 +1,159 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// 历史记录的操作类型
+const (
+	HistoryAdd     = "add"
+	HistoryUpdate  = "update"
+	HistoryDelete  = "delete"
+	HistoryRevert  = "revert"
+	HistoryRestore = "restore"
+)
+
+// secretMask 历史记录中代替密码类字段值的显示内容
+const secretMask = "******"
+
+// FieldChange 一个字段的变化, 密码类字段的值以 secretMask 代替
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+func (c FieldChange) String() string {
+	return fmt.Sprintf("%s: %q -> %q", c.Field, c.Old, c.New)
+}
+
+// HistoryEntry 连接或共享身份的一次修改记录
+type HistoryEntry struct {
+	ID           int64
+	ConnectionID int64
+	// IdentityID 身份的修改记录中为身份的 ID, 此时 ConnectionID 为 0
+	IdentityID int64
+	Action     string
+	// Name 修改后的连接名称, 删除时为删除前的名称
+	Name    string
+	Changes []FieldChange
+	// Before 修改前的连接(密码类字段为密文), 新增连接时为 nil, 用于撤销该修改
+	Before *ConnInfo
+	// BeforeIdentity 修改前的身份(密码类字段为密文), 只在身份的修改记录中使用
+	BeforeIdentity *Identity
+	CreatedAt      time.Time
+}
+
+// differ 收集字段的变化
+type differ struct {
+	changes []FieldChange
+}
+
+func (d *differ) field(name string, o, n any) {
+	a, b := fmt.Sprint(o), fmt.Sprint(n)
+	if a != b {
+		d.changes = append(d.changes, FieldChange{Field: name, Old: a, New: b})
+	}
+}
+
+// secret 比较密码类字段, 解密后相同的密文不算变化
+func (d *differ) secret(name, o, n string) {
+	if o == n {
+		return
+	}
+	po, erro := DecryptString(o)
+	pn, errn := DecryptString(n)
+	if erro == nil && errn == nil && po == pn {
+		return
+	}
+	d.changes = append(d.changes, FieldChange{Field: name, Old: mask(o), New: mask(n)})
+}
+
+// DiffConn 列出从 old 到 conn 发生变化的字段, old 或 conn 为 nil 表示连接不存在
+// 密码类字段解密后比较, 只记录是否变化
+func DiffConn(old, conn *ConnInfo) []FieldChange {
+	var empty ConnInfo
+	if old == nil {
+		old = &empty
+	}
+	if conn == nil {
+		conn = &empty
+	}
+	var d differ
+	d.field("name", old.Name, conn.Name)
+	d.field("host", old.Host, conn.Host)
+	d.field("port", portString(old.Port), portString(conn.Port))
+	// 引用身份的连接使用身份的用户名和认证方式
+	if old.IdentityID == 0 || conn.IdentityID == 0 {
+		d.field("username", old.Username, conn.Username)
+		d.field("auth", authName(old.AuthType), authName(conn.AuthType))
+	}
+	d.secret("password", old.Password, conn.Password)
+	d.field("private_key", old.PrivateKey, conn.PrivateKey)
+	d.secret("key_data", old.KeyData, conn.KeyData)
+	d.secret("key_passphrase", old.KeyPassphrase, conn.KeyPassphrase)
+	d.field("identity", idString(old.IdentityID), idString(conn.IdentityID))
+	oldExtra, newExtra := slices.Sorted(slices.Values(old.ExtraIdentityIDs)), slices.Sorted(slices.Values(conn.ExtraIdentityIDs))
+	if !slices.Equal(oldExtra, newExtra) {
+		d.field("extra_identities", fmt.Sprint(oldExtra), fmt.Sprint(newExtra))
+	}
+	d.secret("totp_secret", old.TOTPSecret, conn.TOTPSecret)
+	d.field("secret_backend", old.SecretBackend, conn.SecretBackend)
+	d.field("secret_ref", old.SecretRef, conn.SecretRef)
+	d.field("notes", old.Notes, conn.Notes)
+	d.field("meta", strings.TrimSpace(FormatMeta(old.Meta)), strings.TrimSpace(FormatMeta(conn.Meta)))
+	return d.changes
+}
+
+// DiffIdentity 列出从 old 到 identity 发生变化的字段, 比较方式与 DiffConn 相同
+func DiffIdentity(old, identity Identity) []FieldChange {
+	var d differ
+	d.field("name", old.Name, identity.Name)
+	d.field("username", old.Username, identity.Username)
+	d.field("auth", authName(old.AuthType), authName(identity.AuthType))
+	d.secret("password", old.Password, identity.Password)
+	d.field("private_key", old.PrivateKey, identity.PrivateKey)
+	d.secret("key_data", old.KeyData, identity.KeyData)
+	d.secret("key_passphrase", old.KeyPassphrase, identity.KeyPassphrase)
+	return d.changes
+}
+
+func mask(s string) string {
+	if s == "" {
+		return ""
+	}
+	return secretMask
+}
+
+func portString(port int) string {
+	if port == 0 {
+		return ""
+	}
+	return fmt.Sprint(port)
+}
+
+func idString(id int64) string {
+	if id == 0 {
+		return ""
+	}
+	return fmt.Sprintf("#%d", id)
+}
+
+func authName(t AuthType) string {
+	switch t {
+	case UsePass:
+		return "password"
+	case UseKey:
+		return "key"
+	}
+	return ""
+}
+
+// TrashedConn 回收站中的连接
+type TrashedConn struct {
+	ConnInfo
+	DeletedAt time.Time
+}
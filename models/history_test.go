@@ -0,0 +1,81 @@
+package models
+
+import (
+	"slices"
+	"testing"
+)
+
+func changedFields(changes []FieldChange) []string {
+	var fields []string
+	for _, c := range changes {
+		fields = append(fields, c.Field)
+	}
+	return fields
+}
+
+func encrypt(t *testing.T, s string) string {
+	t.Helper()
+	enc, err := EncryptString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return enc
+}
+
+func TestDiffConn(t *testing.T) {
+	old := ConnInfo{
+		Name:     "web",
+		Host:     "web.example.com",
+		Port:     22,
+		Username: "root",
+		AuthType: UsePass,
+		Password: encrypt(t, "secret"),
+		Meta:     map[string]string{"env": "prod"},
+	}
+	if changes := DiffConn(&old, &old); len(changes) != 0 {
+		t.Errorf("unchanged connection has changes %v", changes)
+	}
+
+	// 同一密码每次加密的密文不同, 不视为修改
+	reencrypted := old
+	reencrypted.Password = encrypt(t, "secret")
+	if changes := DiffConn(&old, &reencrypted); len(changes) != 0 {
+		t.Errorf("re-encrypted password reported as changed: %v", changes)
+	}
+
+	conn := old
+	conn.Host = "10.0.0.1"
+	conn.Port = 2222
+	conn.Password = encrypt(t, "other")
+	conn.Meta = map[string]string{"env": "staging"}
+	changes := DiffConn(&old, &conn)
+	if got, want := changedFields(changes), []string{"host", "port", "password", "meta"}; !slices.Equal(got, want) {
+		t.Fatalf("changed fields = %v, want %v", got, want)
+	}
+	if changes[0].Old != "web.example.com" || changes[0].New != "10.0.0.1" {
+		t.Errorf("host change = %v", changes[0])
+	}
+	if changes[2].Old != secretMask || changes[2].New != secretMask {
+		t.Errorf("password change is not masked: %v", changes[2])
+	}
+}
+
+func TestDiffConnAddDeleteAndIdentity(t *testing.T) {
+	conn := ConnInfo{Name: "web", Host: "web.example.com", Port: 22, Username: "root", AuthType: UseKey}
+	if got, want := changedFields(DiffConn(nil, &conn)), []string{"name", "host", "port", "username", "auth"}; !slices.Equal(got, want) {
+		t.Errorf("added fields = %v, want %v", got, want)
+	}
+	if got := DiffConn(&conn, nil); len(got) != 5 || got[0].Old != "web" || got[0].New != "" {
+		t.Errorf("deleted changes = %v", got)
+	}
+
+	// 两边都引用身份时用户名和认证方式来自身份, 不比较
+	linked := conn
+	linked.IdentityID = 1
+	relinked := linked
+	relinked.IdentityID = 2
+	relinked.Username = "admin"
+	if got, want := changedFields(DiffConn(&linked, &relinked)), []string{"identity"}; !slices.Equal(got, want) {
+		t.Errorf("changed fields = %v, want %v", got, want)
+	}
+}
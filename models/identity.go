@@ -0,0 +1,25 @@
+package models
+
+// Identity 可被多个连接共享的登录凭据
+type Identity struct {
+	ID            int64    `json:"id"`
+	Name          string   `json:"name" validate:"required"`
+	Username      string   `json:"username" validate:"required"`
+	AuthType      AuthType `json:"auth_type" validate:"required"`
+	Password      string   `json:"password,omitempty"`
+	PrivateKey    string   `json:"private_key,omitempty"`
+	KeyData       string   `json:"key_data,omitempty"`
+	KeyPassphrase string   `json:"key_passphrase,omitempty"`
+}
+
+// ApplyIdentity 使用身份的凭据替换连接自身的凭据
+func (c *ConnInfo) ApplyIdentity(identity Identity) {
+	c.Username = identity.Username
+	c.AuthType = identity.AuthType
+	c.Password = identity.Password
+	c.PrivateKey = identity.PrivateKey
+	c.KeyData = identity.KeyData
+	c.KeyPassphrase = identity.KeyPassphrase
+	c.SecretBackend = SecretBackendLocal
+	c.SecretRef = ""
+}
@@ -0,0 +1,24 @@
+package models
+
+// Override 个人对共享清单中连接的覆盖设置, 按清单名称和连接名称匹配
+// 字段为空表示沿用共享清单中的值
+type Override struct {
+	Source     string `json:"source"`
+	Name       string `json:"name"`
+	Username   string `json:"username,omitempty"`
+	PrivateKey string `json:"private_key,omitempty"`
+}
+
+// ApplyOverride 使用个人的用户名和私钥替换共享连接中的值
+// 设置了私钥时改为使用私钥认证
+func (c *ConnInfo) ApplyOverride(o Override) {
+	if o.Username != "" {
+		c.Username = o.Username
+	}
+	if o.PrivateKey != "" {
+		c.AuthType = UseKey
+		c.PrivateKey = o.PrivateKey
+		c.KeyData = ""
+		c.KeyPassphrase = ""
+	}
+}
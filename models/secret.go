@@ -0,0 +1,11 @@
+package models
+
+// 密码的存储位置
+const (
+	SecretBackendLocal   = ""        // 加密保存在本地数据库中
+	SecretBackendCommand = "command" // 由外部命令输出, 如 pass show、gopass、bw get
+	SecretBackendVault   = "vault"   // 保存在 HashiCorp Vault 的 KV 引擎中
+)
+
+// SecretBackends 可选的密码存储位置
+var SecretBackends = []string{SecretBackendLocal, SecretBackendCommand, SecretBackendVault}
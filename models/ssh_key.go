@@ -0,0 +1,21 @@
+package models
+
+const (
+	KeyTypeEd25519 = "ed25519"
+	KeyTypeRSA     = "rsa"
+	KeyTypeECDSA   = "ecdsa"
+)
+
+// KeyTypes 支持生成的密钥类型
+var KeyTypes = []string{KeyTypeEd25519, KeyTypeRSA, KeyTypeECDSA}
+
+type SSHKey struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name" validate:"required"`
+	KeyType     string `json:"key_type" validate:"required"`
+	PrivateKey  string `json:"private_key,omitempty"` // 私钥内容(加密存储)
+	PublicKey   string `json:"public_key"`            // authorized_keys 格式的公钥
+	Fingerprint string `json:"fingerprint"`
+	// Path 非空表示该密钥来自磁盘上的密钥文件, 未保存在 tssh 中
+	Path string `json:"-"`
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// RFC 6238 附录 B 中 SHA1 的测试向量, 取 8 位验证码的后 6 位
+func TestGenerateTOTPRFC6238(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		code, err := GenerateTOTP(secret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("GenerateTOTP(%d): %v", tt.unix, err)
+		}
+		if code != tt.code {
+			t.Errorf("GenerateTOTP(%d) = %s, want %s", tt.unix, code, tt.code)
+		}
+	}
+}
+
+func TestGenerateTOTPSecretFormats(t *testing.T) {
+	at := time.Unix(59, 0)
+	want := "287082"
+	for _, secret := range []string{
+		"gezd gnbv gy3t qojq gezd gnbv gy3t qojq",
+		"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ====",
+		"otpauth://totp/tssh:test?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&issuer=tssh",
+	} {
+		code, err := GenerateTOTP(secret, at)
+		if err != nil {
+			t.Fatalf("GenerateTOTP(%q): %v", secret, err)
+		}
+		if code != want {
+			t.Errorf("GenerateTOTP(%q) = %s, want %s", secret, code, want)
+		}
+	}
+}
+
+func TestValidateTOTPSecret(t *testing.T) {
+	for _, secret := range []string{"not base32!", "otpauth://totp/tssh:test?issuer=tssh"} {
+		if err := ValidateTOTPSecret(secret); err == nil {
+			t.Errorf("ValidateTOTPSecret(%q) succeeded, want error", secret)
+		}
+	}
+}
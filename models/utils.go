@@ -98,13 +98,25 @@ func EncryptString(plaintext string) (string, error) {
 	return base64.StdEncoding.EncodeToString(encryptedBytes), nil
 }
 func DecryptString(ciphertext string) (string, error) {
-	ciphertextBytes, err := base64.StdEncoding.DecodeString(ciphertext)
+	decryptedBytes, err := DecryptBytes(ciphertext)
 	if err != nil {
 		return "", err
 	}
-	decryptedBytes, err := DecryptAESCBC(commonKeyBytes, ciphertextBytes)
+	return string(decryptedBytes), nil
+}
+
+// DecryptBytes 解密为字节切片, 调用方使用完毕后应调用 Zero 清除明文
+func DecryptBytes(ciphertext string) ([]byte, error) {
+	ciphertextBytes, err := base64.StdEncoding.DecodeString(ciphertext)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	return DecryptAESCBC(commonKeyBytes, ciphertextBytes)
+}
+
+// Zero 将内存中的敏感数据清零
+func Zero(b []byte) {
+	for i := range b {
+		b[i] = 0
 	}
-	return string(decryptedBytes), nil
 }
@@ -0,0 +1,38 @@
+package secret
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// commandBackend 通过外部命令获取密码, ref 为交给 sh -c 执行的命令
+// 使用命令输出的第一行作为密码, 例如 "pass show servers/web" 或 "bw get password web"
+// 命令不读取 tssh 的标准输入, 需要交互的程序 (如 pinentry) 应自行打开终端
+type commandBackend struct{}
+
+func (commandBackend) Resolve(ref string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("sh", "-c", ref)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("credential helper failed: %v %s", err, strings.TrimSpace(stderr.String()))
+	}
+	line := out
+	if i := bytes.IndexByte(out, '\n'); i >= 0 {
+		line = out[:i]
+	}
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	pass := make([]byte, len(line))
+	copy(pass, line)
+	for i := range out {
+		out[i] = 0
+	}
+	if len(pass) == 0 {
+		return nil, errors.New("credential helper returned an empty password")
+	}
+	return pass, nil
+}
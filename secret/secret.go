@@ -0,0 +1,52 @@
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"tssh/models"
+)
+
+// Backend 密码的存储后端, ref 为连接中保存的密码引用
+type Backend interface {
+	Resolve(ref string) ([]byte, error)
+}
+
+// Get 返回指定名称的后端
+func Get(name string) (Backend, error) {
+	switch name {
+	case models.SecretBackendLocal:
+		return localBackend{}, nil
+	case models.SecretBackendCommand:
+		return commandBackend{}, nil
+	case models.SecretBackendVault:
+		return newVaultBackend(), nil
+	}
+	return nil, fmt.Errorf("unknown secret backend: %s", name)
+}
+
+// Password 从连接配置的后端中读取密码, 调用方使用完毕后应调用 models.Zero 清除
+func Password(conn *models.ConnInfo) ([]byte, error) {
+	backend, err := Get(conn.SecretBackend)
+	if err != nil {
+		return nil, err
+	}
+	ref := conn.SecretRef
+	if conn.SecretBackend == models.SecretBackendLocal {
+		ref = conn.Password
+	}
+	if ref == "" {
+		return nil, errors.New("no password configured")
+	}
+	return backend.Resolve(ref)
+}
+
+// localBackend 本地数据库中加密保存的密码, ref 为密文
+type localBackend struct{}
+
+func (localBackend) Resolve(ref string) ([]byte, error) {
+	pass, err := models.DecryptBytes(ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt password: %w", err)
+	}
+	return pass, nil
+}
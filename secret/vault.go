@@ -0,0 +1,96 @@
+package secret
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const defaultVaultAddr = "http://127.0.0.1:8200"
+
+// vaultBackend 从 HashiCorp Vault 的 KV 引擎读取密码
+// ref 格式为 "路径#字段", 例如 "secret/data/servers/web#password", 字段默认为 password
+// 地址和令牌通过 VAULT_ADDR、VAULT_TOKEN (或 ~/.vault-token)、VAULT_NAMESPACE 配置
+type vaultBackend struct {
+	addr      string
+	token     string
+	namespace string
+	client    *http.Client
+}
+
+func newVaultBackend() *vaultBackend {
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		addr = defaultVaultAddr
+	}
+	token := os.Getenv("VAULT_TOKEN")
+	if token == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			if data, err := os.ReadFile(filepath.Join(home, ".vault-token")); err == nil {
+				token = strings.TrimSpace(string(data))
+			}
+		}
+	}
+	return &vaultBackend{
+		addr:      strings.TrimRight(addr, "/"),
+		token:     token,
+		namespace: os.Getenv("VAULT_NAMESPACE"),
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (v *vaultBackend) Resolve(ref string) ([]byte, error) {
+	path, field, _ := strings.Cut(ref, "#")
+	if field == "" {
+		field = "password"
+	}
+	if v.token == "" {
+		return nil, fmt.Errorf("vault token is not set, export VAULT_TOKEN or run vault login")
+	}
+	req, err := http.NewRequest(http.MethodGet, v.addr+"/v1/"+strings.TrimLeft(path, "/"), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		// 错误响应的格式为 {"errors": ["..."]}
+		var failure struct {
+			Errors []string `json:"errors"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&failure) == nil && len(failure.Errors) > 0 {
+			return nil, fmt.Errorf("vault returned %s for %s: %s", resp.Status, path, strings.Join(failure.Errors, "; "))
+		}
+		return nil, fmt.Errorf("vault returned %s for %s", resp.Status, path)
+	}
+
+	var body struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	data := body.Data
+	// KV v2 的数据位于 data.data 中
+	if inner, ok := data["data"].(map[string]any); ok {
+		if _, isMeta := data["metadata"]; isMeta {
+			data = inner
+		}
+	}
+	value, ok := data[field].(string)
+	if !ok || value == "" {
+		return nil, fmt.Errorf("field %q not found at %s", field, path)
+	}
+	return []byte(value), nil
+}
@@ -0,0 +1,98 @@
+package secret
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestVault(t *testing.T) *vaultBackend {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/kv1/web", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": {"password": "v1-pass", "root": "v1-root"}}`))
+	})
+	mux.HandleFunc("/v1/secret/data/web", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Namespace") != "team" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors": ["permission denied"]}`))
+			return
+		}
+		w.Write([]byte(`{"data": {"data": {"password": "v2-pass", "root": "v2-root"}, "metadata": {"version": 3}}}`))
+	})
+	// KV v1 中名为 data 的字段不应被当作 KV v2 的数据
+	mux.HandleFunc("/v1/kv1/nested", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": {"data": {"password": "inner"}, "password": "outer"}}`))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors": ["permission denied"]}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"errors": []}`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return &vaultBackend{
+		addr:      server.URL,
+		token:     "token",
+		namespace: "team",
+		client:    server.Client(),
+	}
+}
+
+func TestVaultResolve(t *testing.T) {
+	v := newTestVault(t)
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{"kv1/web", "v1-pass"},
+		{"/kv1/web#root", "v1-root"},
+		{"secret/data/web", "v2-pass"},
+		{"secret/data/web#root", "v2-root"},
+		{"kv1/nested", "outer"},
+	}
+	for _, tt := range tests {
+		got, err := v.Resolve(tt.ref)
+		if err != nil {
+			t.Fatalf("Resolve(%q): %v", tt.ref, err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("Resolve(%q) = %q, want %q", tt.ref, got, tt.want)
+		}
+	}
+}
+
+func TestVaultResolveErrors(t *testing.T) {
+	v := newTestVault(t)
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{"kv1/web#missing", `field "missing" not found`},
+		{"secret/missing", "404"},
+	}
+	for _, tt := range tests {
+		_, err := v.Resolve(tt.ref)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Resolve(%q) error = %v, want it to contain %q", tt.ref, err, tt.want)
+		}
+	}
+
+	v.namespace = ""
+	if _, err := v.Resolve("secret/data/web"); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("Resolve without namespace error = %v, want vault error message", err)
+	}
+	v.token = "wrong"
+	if _, err := v.Resolve("secret/other"); err == nil || !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("Resolve with wrong token error = %v, want 403 with vault error message", err)
+	}
+	v.token = ""
+	if _, err := v.Resolve("kv1/web"); err == nil || !strings.Contains(err.Error(), "token") {
+		t.Errorf("Resolve without token error = %v, want missing token error", err)
+	}
+}
@@ -0,0 +1,79 @@
+package ssh
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"tssh/models"
+
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// startAgent 启动仅在本次连接期间存在的 ssh-agent 提供导入的私钥, 私钥只保存在内存中
+// 私钥受密码保护且未保存密码时在终端提示输入
+// 返回需要传递给 ssh 的参数和停止函数
+func startAgent(data, passphrase []byte) ([]string, func(), error) {
+	key, err := parseAgentKey(data, passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: key}); err != nil {
+		return nil, nil, err
+	}
+	dir, err := os.MkdirTemp("", "tssh-agent-")
+	if err != nil {
+		keyring.RemoveAll()
+		return nil, nil, err
+	}
+	sockPath := filepath.Join(dir, "sock")
+	listener, err := net.Listen("unix", sockPath)
+	if err != nil {
+		keyring.RemoveAll()
+		os.RemoveAll(dir)
+		return nil, nil, err
+	}
+
+	go func() {
+		for {
+			c, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				agent.ServeAgent(keyring, c)
+			}()
+		}
+	}()
+
+	// 命令行参数优先于 ssh_config 中的 IdentityAgent
+	args := []string{"-o", "IdentityAgent=" + sockPath}
+	stop := func() {
+		listener.Close()
+		keyring.RemoveAll()
+		os.RemoveAll(dir)
+	}
+	return args, stop, nil
+}
+
+// parseAgentKey 解析导入的私钥, 缺少私钥密码时在终端提示输入
+func parseAgentKey(data, passphrase []byte) (any, error) {
+	if len(passphrase) > 0 {
+		return gossh.ParseRawPrivateKeyWithPassphrase(data, passphrase)
+	}
+	key, err := gossh.ParseRawPrivateKey(data)
+	var missing *gossh.PassphraseMissingError
+	if !errors.As(err, &missing) {
+		return key, err
+	}
+	value, err := promptTerminal("Enter passphrase for imported key: ")
+	if err != nil {
+		return nil, err
+	}
+	input := []byte(value)
+	defer models.Zero(input)
+	return gossh.ParseRawPrivateKeyWithPassphrase(data, input)
+}
@@ -0,0 +1,219 @@
+package ssh
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"tssh/models"
+
+	"golang.org/x/term"
+)
+
+// tssh 作为 SSH_ASKPASS 程序运行时使用的环境变量
+const (
+	envAskpassSock  = "TSSH_ASKPASS_SOCK"
+	envAskpassToken = "TSSH_ASKPASS_TOKEN"
+)
+
+var (
+	otpPrompt        = regexp.MustCompile(`(?i)(verification code|one[- ]time|otp|token|2fa|authenticator|passcode)`)
+	passphrasePrompt = regexp.MustCompile(`(?i)passphrase`)
+	passwordPrompt   = regexp.MustCompile(`(?i)password`)
+)
+
+// askpassAnswers 用于自动应答 ssh 提示的凭据
+type askpassAnswers struct {
+	password   []byte
+	passphrase []byte
+	totpSecret []byte
+}
+
+// zero 清除内存中的凭据
+func (a *askpassAnswers) zero() {
+	models.Zero(a.password)
+	models.Zero(a.passphrase)
+	models.Zero(a.totpSecret)
+}
+
+// answer 根据提示内容返回应答, 无法应答时返回 false
+// 密码类提示只应答一次, 避免凭据错误时反复重试
+func (a *askpassAnswers) answer(prompt string, answered map[string]bool) ([]byte, bool) {
+	var kind string
+	var value []byte
+	switch {
+	case len(a.totpSecret) > 0 && otpPrompt.MatchString(prompt):
+		code, err := models.GenerateTOTP(string(a.totpSecret), time.Now())
+		if err != nil {
+			return nil, false
+		}
+		kind, value = "totp", []byte(code)
+	case len(a.passphrase) > 0 && passphrasePrompt.MatchString(prompt):
+		kind, value = "passphrase", a.passphrase
+	case len(a.password) > 0 && passwordPrompt.MatchString(prompt):
+		kind, value = "password", a.password
+	default:
+		return nil, false
+	}
+	if answered[kind] {
+		return nil, false
+	}
+	answered[kind] = true
+	return value, true
+}
+
+// startAskpass 启动应答服务, 返回需要传递给 ssh 的环境变量和停止函数
+// 应答服务监听仅当前用户可访问的 unix socket, 并校验一次性令牌
+// 停止应答服务时会清除 answers 中的凭据
+func startAskpass(answers *askpassAnswers) ([]string, func(), error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, nil, err
+	}
+	dir, err := os.MkdirTemp("", "tssh-askpass-")
+	if err != nil {
+		return nil, nil, err
+	}
+	sockPath := filepath.Join(dir, "sock")
+	listener, err := net.Listen("unix", sockPath)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, nil, err
+	}
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		listener.Close()
+		os.RemoveAll(dir)
+		return nil, nil, err
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	answered := make(map[string]bool)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			c, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			serveAskpass(c, token, answers, answered)
+			mu.Unlock()
+		}
+	}()
+
+	// SSH_ASKPASS_REQUIRE 需要 OpenSSH 8.4 及以上版本, 此时不需要设置 DISPLAY
+	env := []string{
+		"SSH_ASKPASS=" + exe,
+		"SSH_ASKPASS_REQUIRE=force",
+		envAskpassSock + "=" + sockPath,
+		envAskpassToken + "=" + token,
+	}
+	stop := func() {
+		listener.Close()
+		wg.Wait()
+		os.RemoveAll(dir)
+		answers.zero()
+	}
+	return env, stop, nil
+}
+
+// serveAskpass 处理一次应答请求
+// 请求为 "令牌\n提示", 响应以 '1' 开头表示后续为应答内容, '0' 表示需要用户输入
+func serveAskpass(c net.Conn, token string, answers *askpassAnswers, answered map[string]bool) {
+	defer c.Close()
+	c.SetDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(c)
+	gotToken, err := r.ReadString('\n')
+	if err != nil {
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(strings.TrimSuffix(gotToken, "\n")), []byte(token)) != 1 {
+		return
+	}
+	prompt, err := io.ReadAll(r)
+	if err != nil {
+		return
+	}
+	if value, ok := answers.answer(string(prompt), answered); ok {
+		c.Write([]byte{'1'})
+		c.Write(value)
+		return
+	}
+	c.Write([]byte("0"))
+}
+
+// IsAskpass 判断当前进程是否作为 ssh 的 SSH_ASKPASS 程序被调用
+func IsAskpass() bool {
+	return os.Getenv(envAskpassSock) != "" && os.Getenv(envAskpassToken) != ""
+}
+
+// RunAskpass 作为 SSH_ASKPASS 程序运行, 将应答输出到标准输出并返回退出码
+// args 为 ssh 传入的参数, 第一个参数是提示内容
+func RunAskpass(args []string) int {
+	prompt := ""
+	if len(args) > 0 {
+		prompt = args[0]
+	}
+	if value, ok := requestAnswer(prompt); ok {
+		fmt.Println(value)
+		return 0
+	}
+	value, err := promptTerminal(prompt)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tssh askpass: %v\n", err)
+		return 1
+	}
+	fmt.Println(value)
+	return 0
+}
+
+func requestAnswer(prompt string) (string, bool) {
+	c, err := net.Dial("unix", os.Getenv(envAskpassSock))
+	if err != nil {
+		return "", false
+	}
+	defer c.Close()
+	c.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := c.Write([]byte(os.Getenv(envAskpassToken) + "\n" + prompt)); err != nil {
+		return "", false
+	}
+	if uc, ok := c.(*net.UnixConn); ok {
+		uc.CloseWrite()
+	}
+	resp, err := io.ReadAll(c)
+	if err != nil || len(resp) == 0 || resp[0] != '1' {
+		return "", false
+	}
+	return string(resp[1:]), true
+}
+
+// promptTerminal 无法自动应答时在终端提示用户输入
+// 提示内容包含 yes/no 时回显输入, 否则按密码方式读取
+func promptTerminal(prompt string) (string, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return "", err
+	}
+	defer tty.Close()
+	fmt.Fprint(tty, prompt)
+	if strings.Contains(prompt, "(yes/no") {
+		line, err := bufio.NewReader(tty).ReadString('\n')
+		return strings.TrimRight(line, "\r\n"), err
+	}
+	value, err := term.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(tty)
+	return string(value), err
+}
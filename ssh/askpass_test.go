@@ -0,0 +1,95 @@
+package ssh
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"tssh/models"
+)
+
+// startTestAskpass 启动应答服务并设置 RunAskpass 使用的环境变量
+func startTestAskpass(t *testing.T, answers *askpassAnswers) (sockPath string, stop func()) {
+	t.Helper()
+	env, stop, err := startAskpass(answers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(stop)
+	for _, kv := range env {
+		k, v, _ := strings.Cut(kv, "=")
+		if k == envAskpassSock || k == envAskpassToken {
+			t.Setenv(k, v)
+		}
+		if k == envAskpassSock {
+			sockPath = v
+		}
+	}
+	return sockPath, stop
+}
+
+func TestAskpassAnswers(t *testing.T) {
+	startTestAskpass(t, &askpassAnswers{
+		password:   []byte("secret"),
+		passphrase: []byte("key-pass"),
+		totpSecret: []byte(testTOTPSecret),
+	})
+	code, err := models.GenerateTOTP(testTOTPSecret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		prompt string
+		want   string
+		ok     bool
+	}{
+		{"root@10.0.0.1's password: ", "secret", true},
+		{"Enter passphrase for key '/root/.ssh/id_ed25519': ", "key-pass", true},
+		{"Verification code: ", code, true},
+		// 每类凭据只应答一次, 凭据错误时交给用户输入
+		{"root@10.0.0.1's password: ", "", false},
+		{"Enter passphrase for key '/root/.ssh/id_ed25519': ", "", false},
+		// 无法识别的提示不应答
+		{"Are you sure you want to continue connecting (yes/no)? ", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := requestAnswer(tt.prompt)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("requestAnswer(%q) = %q, %v, want %q, %v", tt.prompt, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestAskpassRejectsWrongToken(t *testing.T) {
+	startTestAskpass(t, &askpassAnswers{password: []byte("secret")})
+	token := os.Getenv(envAskpassToken)
+	t.Setenv(envAskpassToken, strings.Repeat("0", len(token)))
+	if got, ok := requestAnswer("Password: "); ok {
+		t.Fatalf("wrong token answered %q", got)
+	}
+	// 错误的令牌不会消耗应答
+	t.Setenv(envAskpassToken, token)
+	if got, ok := requestAnswer("Password: "); !ok || got != "secret" {
+		t.Errorf("requestAnswer = %q, %v", got, ok)
+	}
+}
+
+func TestAskpassStop(t *testing.T) {
+	answers := &askpassAnswers{password: []byte("secret")}
+	sockPath, stop := startTestAskpass(t, answers)
+	if info, err := os.Stat(filepath.Dir(sockPath)); err != nil || info.Mode().Perm() != 0700 {
+		t.Fatalf("socket dir: %v, %v", info, err)
+	}
+	stop()
+	if _, err := os.Stat(filepath.Dir(sockPath)); !os.IsNotExist(err) {
+		t.Errorf("socket dir not removed: %v", err)
+	}
+	if string(answers.password) != strings.Repeat("\x00", len("secret")) {
+		t.Errorf("password not zeroed: %q", answers.password)
+	}
+	if _, ok := requestAnswer("Password: "); ok {
+		t.Error("answered after stop")
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+	"tssh/config"
 	"tssh/models"
+	"tssh/secret"
 )
 
 func GetValidPath(inputPath string, defaultPath string) string {
@@ -16,27 +18,77 @@ func GetValidPath(inputPath string, defaultPath string) string {
 	}
 	return defaultPath
 }
-func Connect(rctx *models.RunContext) {
+
+// Connect 使用 OpenSSH 客户端连接服务器
+// 密码、私钥密码和 TOTP 验证码均由 tssh 作为 SSH_ASKPASS 程序应答
+func Connect(rctx *models.RunContext, opts config.Connect) {
 	conn := rctx.Context
 	protArg := "-p"
 	if rctx.Command == models.RunCommandSftp {
 		protArg = "-P"
 	}
 	userHost := fmt.Sprintf("%s@%s", conn.Username, conn.Host)
-	var cmd *exec.Cmd
+	args := []string{protArg, fmt.Sprintf("%d", conn.Port)}
+	if opts.StrictHostKeyChecking != "" {
+		args = append(args, "-o", "StrictHostKeyChecking="+opts.StrictHostKeyChecking)
+	}
+	args = append(args, opts.ExtraArgs...)
+	// 用户名或主机以 - 开头时不能被 ssh 当作选项解析
+	args = append(args, "--", userHost)
+
+	answers := &askpassAnswers{}
+	defer answers.zero()
+	if conn.TOTPSecret != "" {
+		secret, err := models.DecryptBytes(conn.TOTPSecret)
+		if err != nil {
+			fmt.Printf("Failed to decrypt totp secret: %v\n", err)
+			return
+		}
+		answers.totpSecret = secret
+	}
 	switch conn.AuthType {
 	case models.UsePass:
-		pass, err := models.DecryptString(conn.Password)
+		// 密码在连接时才从配置的后端中读取
+		pass, err := secret.Password(conn)
 		if err != nil {
-			fmt.Printf("Failed to decrypt password: %v\n", err)
+			fmt.Printf("Failed to get password: %v\n", err)
 			return
 		}
-		cmd = exec.Command("sshpass", "-p", pass, string(rctx.Command), "-o", "StrictHostKeyChecking=no", protArg, fmt.Sprintf("%d", conn.Port), userHost)
+		answers.password = pass
 	case models.UseKey:
 		keyPath := strings.TrimSpace(conn.PrivateKey)
-		keyPath = GetValidPath(keyPath, "~/.ssh/id_rsa")
-		cmd = exec.Command(string(rctx.Command), "-i", keyPath, "-o", "StrictHostKeyChecking=no", protArg, fmt.Sprintf("%d", conn.Port), userHost)
+		keyPath = GetValidPath(ExpandPath(keyPath), ExpandPath(opts.DefaultKey))
+		data, passphrase, err := keyMaterial(conn)
+		if err != nil {
+			fmt.Printf("Failed to load private key: %v\n", err)
+			return
+		}
+		if data == nil {
+			answers.passphrase = passphrase
+			args = append([]string{"-i", keyPath}, args...)
+			break
+		}
+		// 导入的私钥通过临时 ssh-agent 提供, 不写入磁盘
+		agentArgs, stopAgent, err := startAgent(data, passphrase)
+		models.Zero(data)
+		models.Zero(passphrase)
+		if err != nil {
+			fmt.Printf("Failed to load private key: %v\n", err)
+			return
+		}
+		defer stopAgent()
+		args = append(agentArgs, args...)
+	}
+	cmd := exec.Command(string(rctx.Command), args...)
+
+	env, stop, err := startAskpass(answers)
+	if err != nil {
+		fmt.Printf("Failed to start askpass helper: %v\n", err)
+		return
 	}
+	defer stop()
+	cmd.Env = append(os.Environ(), env...)
+
 	// 绑定标准输入、输出和错误到当前终端
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
@@ -0,0 +1,196 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"tssh/config"
+	"tssh/database"
+	"tssh/models"
+	"tssh/secret"
+
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const dialTimeout = 10 * time.Second
+
+func dial(conn *models.ConnInfo, hostKey gossh.HostKeyCallback, auth ...gossh.AuthMethod) (*gossh.Client, error) {
+	config := &gossh.ClientConfig{
+		User:            conn.Username,
+		Auth:            auth,
+		HostKeyCallback: hostKey,
+		Timeout:         dialTimeout,
+	}
+	addr := net.JoinHostPort(conn.Host, strconv.Itoa(conn.Port))
+	return gossh.Dial("tcp", addr, config)
+}
+
+// hostKeyCallback 按 StrictHostKeyChecking 的设置校验主机密钥, 与连接时 ssh 的行为一致
+// no 时不校验; accept-new 时将未知主机的密钥加入 known_hosts; 其余取值只接受 known_hosts 中已有的主机
+func hostKeyCallback(strict string) (gossh.HostKeyCallback, error) {
+	if strict == "no" || strict == "off" {
+		return gossh.InsecureIgnoreHostKey(), nil
+	}
+	path := ExpandPath("~/.ssh/known_hosts")
+	check, err := knownhosts.New(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		// known_hosts 不存在时所有主机都是未知的
+		check = func(string, net.Addr, gossh.PublicKey) error { return &knownhosts.KeyError{} }
+	} else if err != nil {
+		return nil, err
+	}
+	return func(hostname string, remote net.Addr, key gossh.PublicKey) error {
+		err := check(hostname, remote, key)
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) || len(keyErr.Want) > 0 {
+			return err
+		}
+		if strict != "accept-new" {
+			return fmt.Errorf("host key of %s is not in %s, connect with ssh once to verify it", hostname, path)
+		}
+		return addKnownHost(path, hostname, key)
+	}, nil
+}
+
+// addKnownHost 将主机密钥追加到 known_hosts
+func addKnownHost(path, hostname string, key gossh.PublicKey) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintln(f, knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key))
+	return err
+}
+
+// credentialAuth 使用密码登录, keyboard-interactive 认证时只应答密码和验证码提示
+func credentialAuth(answers *askpassAnswers) []gossh.AuthMethod {
+	answered := make(map[string]bool)
+	return []gossh.AuthMethod{
+		gossh.PasswordCallback(func() (string, error) {
+			return string(answers.password), nil
+		}),
+		gossh.KeyboardInteractive(func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+			replies := make([]string, len(questions))
+			for i, question := range questions {
+				value, ok := answers.answer(question, answered)
+				if !ok {
+					return nil, fmt.Errorf("cannot answer prompt %q", strings.TrimSpace(question))
+				}
+				replies[i] = string(value)
+			}
+			return replies, nil
+		}),
+	}
+}
+
+// shellQuote 使用单引号转义 shell 参数
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// InstallPublicKey 使用连接当前的密码认证将公钥追加到服务器的 authorized_keys,
+// 随后使用该密钥重新登录以验证安装结果
+// 私钥受密码保护且 passphrase 为空时返回 *gossh.PassphraseMissingError, 调用方应提示输入后重试
+func InstallPublicKey(conn *models.ConnInfo, key models.SSHKey, passphrase []byte, opts config.Connect) error {
+	if conn.Source != "" {
+		return database.ErrReadOnly
+	}
+	if conn.AuthType != models.UsePass {
+		return errors.New("installing a key requires password authentication")
+	}
+	privateData, err := keyPrivateData(key)
+	if err != nil {
+		return err
+	}
+	defer models.Zero(privateData)
+	var signer gossh.Signer
+	if len(passphrase) == 0 {
+		signer, err = gossh.ParsePrivateKey(privateData)
+	} else {
+		signer, err = gossh.ParsePrivateKeyWithPassphrase(privateData, passphrase)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load private key: %w", err)
+	}
+	hostKey, err := hostKeyCallback(opts.StrictHostKeyChecking)
+	if err != nil {
+		return err
+	}
+	answers := &askpassAnswers{}
+	defer answers.zero()
+	if answers.password, err = secret.Password(conn); err != nil {
+		return err
+	}
+	if conn.TOTPSecret != "" {
+		if answers.totpSecret, err = models.DecryptBytes(conn.TOTPSecret); err != nil {
+			return fmt.Errorf("failed to decrypt totp secret: %w", err)
+		}
+	}
+
+	client, err := dial(conn, hostKey, credentialAuth(answers)...)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	session, err := client.NewSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	cmd := installKeyScript(key.PublicKey)
+	if out, err := session.CombinedOutput(cmd); err != nil {
+		return fmt.Errorf("failed to install public key: %v %s", err, strings.TrimSpace(string(out)))
+	}
+
+	verify, err := dial(conn, hostKey, gossh.PublicKeys(signer))
+	if err != nil {
+		return fmt.Errorf("public key installed but key login failed: %w", err)
+	}
+	return verify.Close()
+}
+
+// installKeyScript 返回将公钥追加到 authorized_keys 的 shell 命令, 公钥已存在时不重复添加
+// 文件末尾没有换行时先补上换行, 以免新的公钥接在最后一行之后
+func installKeyScript(publicKey string) string {
+	pub := shellQuote(publicKey)
+	const file = "~/.ssh/authorized_keys"
+	return "umask 077; mkdir -p ~/.ssh && touch " + file + " && " +
+		"(grep -qxF " + pub + " " + file + " || " +
+		"{ if [ -s " + file + " ] && [ -n \"$(tail -c1 " + file + ")\" ]; then echo >> " + file + "; fi; " +
+		"echo " + pub + " >> " + file + "; })"
+}
+
+// SwitchToKey 将连接切换为使用指定密钥认证, 并解除与共享身份的关联
+// 保存在 tssh 中的密钥导入到连接, 磁盘上的密钥只记录路径; 私钥密码非空时一并保存
+// conn 为存储中读取的连接, 其中的密码类字段是密文, 清空后更新时保留原值, 以免被当作明文再次加密
+func SwitchToKey(conn models.ConnInfo, key models.SSHKey, passphrase []byte) (models.ConnInfo, error) {
+	conn.IdentityID = 0
+	conn.AuthType = models.UseKey
+	conn.Password = ""
+	conn.KeyPassphrase = string(passphrase)
+	conn.TOTPSecret = ""
+	if key.Path != "" {
+		conn.PrivateKey = key.Path
+		conn.KeyData = ""
+		return conn, nil
+	}
+	data, err := keyPrivateData(key)
+	if err != nil {
+		return conn, err
+	}
+	conn.PrivateKey = ""
+	conn.KeyData = string(data)
+	return conn, nil
+}
@@ -0,0 +1,175 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"tssh/config"
+	"tssh/database"
+	"tssh/models"
+
+	gossh "golang.org/x/crypto/ssh"
+)
+
+const testTOTPSecret = "JBSWY3DPEHPK3PXP"
+
+func decrypt(t *testing.T, ciphertext string) string {
+	t.Helper()
+	plain, err := models.DecryptString(ciphertext)
+	if err != nil {
+		t.Fatalf("decrypt: %v", err)
+	}
+	return plain
+}
+
+// 切换为密钥认证后写回存储, 已保存的密文不能被再次加密
+func TestSwitchToKeyKeepsStoredSecrets(t *testing.T) {
+	tests := []struct {
+		name       string
+		passphrase []byte
+		want       string
+	}{
+		{"keep passphrase", nil, "old-pass"},
+		{"new passphrase", []byte("new-pass"), "new-pass"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := database.NewDB(filepath.Join(t.TempDir(), "tssh.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			err = db.AddConnection(models.ConnInfo{
+				Name: "web", Host: "10.0.0.1", Port: 22, Username: "root",
+				AuthType: models.UsePass, Password: "secret",
+				KeyPassphrase: "old-pass", TOTPSecret: testTOTPSecret,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			all, err := db.GetAllConnections()
+			if err != nil || len(all) != 1 {
+				t.Fatalf("GetAllConnections = %v, %v", all, err)
+			}
+
+			conn, err := SwitchToKey(all[0], models.SSHKey{Name: "id", Path: "/home/me/.ssh/id_ed25519"}, tt.passphrase)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := db.UpdateConnection(conn); err != nil {
+				t.Fatalf("UpdateConnection: %v", err)
+			}
+
+			got, err := db.GetConnection(conn.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.AuthType != models.UseKey || got.PrivateKey != "/home/me/.ssh/id_ed25519" {
+				t.Errorf("auth = %v %q", got.AuthType, got.PrivateKey)
+			}
+			if p := decrypt(t, got.KeyPassphrase); p != tt.want {
+				t.Errorf("passphrase = %q, want %q", p, tt.want)
+			}
+			if s := decrypt(t, got.TOTPSecret); s != testTOTPSecret {
+				t.Errorf("totp secret = %q", s)
+			}
+		})
+	}
+}
+
+func TestInstallKeyScript(t *testing.T) {
+	const pub = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIAbc user@host"
+	tests := []struct {
+		name     string
+		existing string
+		want     string
+	}{
+		{"no file", "", pub + "\n"},
+		{"missing newline", "ssh-rsa AAAA old", "ssh-rsa AAAA old\n" + pub + "\n"},
+		{"trailing newline", "ssh-rsa AAAA old\n", "ssh-rsa AAAA old\n" + pub + "\n"},
+		{"already installed", pub + "\n", pub + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			file := filepath.Join(home, ".ssh", "authorized_keys")
+			if tt.existing != "" {
+				os.MkdirAll(filepath.Dir(file), 0700)
+				if err := os.WriteFile(file, []byte(tt.existing), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// 重复执行不会重复添加
+			for range 2 {
+				cmd := exec.Command("sh", "-c", installKeyScript(pub))
+				cmd.Env = append(os.Environ(), "HOME="+home)
+				if out, err := cmd.CombinedOutput(); err != nil {
+					t.Fatalf("%v: %s", err, out)
+				}
+			}
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("authorized_keys = %q, want %q", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestHostKeyCallback(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	newKey := func() gossh.PublicKey {
+		pub, _, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, err := gossh.NewPublicKey(pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key
+	}
+	key, other := newKey(), newKey()
+	remote := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 22}
+	check := func(strict string, key gossh.PublicKey) error {
+		t.Helper()
+		callback, err := hostKeyCallback(strict)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return callback("10.0.0.1:22", remote, key)
+	}
+
+	if err := check("no", key); err != nil {
+		t.Errorf("no: %v", err)
+	}
+	// 未知主机只在 accept-new 时接受
+	if err := check("yes", key); err == nil {
+		t.Error("yes accepted an unknown host")
+	}
+	if err := check("accept-new", key); err != nil {
+		t.Fatalf("accept-new: %v", err)
+	}
+	if err := check("yes", key); err != nil {
+		t.Errorf("yes rejected a known host: %v", err)
+	}
+	// 主机密钥变化时总是拒绝
+	if err := check("accept-new", other); err == nil {
+		t.Error("accept-new accepted a changed host key")
+	}
+}
+
+func TestInstallPublicKeyReadOnly(t *testing.T) {
+	conn := &models.ConnInfo{Name: "web", Host: "10.0.0.1", Port: 22, AuthType: models.UsePass, Source: "team"}
+	if err := InstallPublicKey(conn, models.SSHKey{Name: "id"}, nil, config.Connect{}); !errors.Is(err, database.ErrReadOnly) {
+		t.Errorf("installing on a shared connection returned %v, want ErrReadOnly", err)
+	}
+}
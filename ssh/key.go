@@ -0,0 +1,99 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"tssh/models"
+
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// ExpandPath 展开路径开头的 ~ 为用户主目录
+func ExpandPath(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(homeDir, path[1:])
+}
+
+// ReadPrivateKey 读取私钥文件内容并校验其格式
+// passphrase 为空且私钥受密码保护时同样视为有效
+func ReadPrivateKey(path string, passphrase string) ([]byte, error) {
+	data, err := os.ReadFile(ExpandPath(strings.TrimSpace(path)))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := parseRawKey(data, passphrase); err != nil {
+		var missing *gossh.PassphraseMissingError
+		if passphrase == "" && errors.As(err, &missing) {
+			return data, nil
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+func parseRawKey(data []byte, passphrase string) (any, error) {
+	if passphrase == "" {
+		return gossh.ParseRawPrivateKey(data)
+	}
+	return gossh.ParseRawPrivateKeyWithPassphrase(data, []byte(passphrase))
+}
+
+// keyMaterial 返回导入的私钥内容和私钥密码(已解密), 调用方使用完毕后应清除
+// 私钥未导入时 data 为 nil, 由 ssh 自行读取密钥文件
+func keyMaterial(conn *models.ConnInfo) (data []byte, passphrase []byte, err error) {
+	if conn.KeyPassphrase != "" {
+		passphrase, err = models.DecryptBytes(conn.KeyPassphrase)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decrypt key passphrase: %w", err)
+		}
+	}
+	if conn.KeyData != "" {
+		data, err = models.DecryptBytes(conn.KeyData)
+		if err != nil {
+			models.Zero(passphrase)
+			return nil, nil, fmt.Errorf("failed to decrypt private key: %w", err)
+		}
+	}
+	return data, passphrase, nil
+}
+
+// KeyFingerprint 返回连接使用的私钥的 SHA256 指纹, 私钥路径的选择与 Connect 相同
+// 私钥文件旁有 .pub 公钥时直接读取公钥, 否则解析私钥
+func KeyFingerprint(conn *models.ConnInfo, defaultKey string) (string, error) {
+	data, passphrase, err := keyMaterial(conn)
+	if err != nil {
+		return "", err
+	}
+	defer models.Zero(passphrase)
+	if data == nil {
+		path := GetValidPath(ExpandPath(strings.TrimSpace(conn.PrivateKey)), ExpandPath(defaultKey))
+		if pub, err := os.ReadFile(path + ".pub"); err == nil {
+			if key, _, _, _, err := gossh.ParseAuthorizedKey(pub); err == nil {
+				return gossh.FingerprintSHA256(key), nil
+			}
+		}
+		if data, err = os.ReadFile(path); err != nil {
+			return "", err
+		}
+	}
+	defer models.Zero(data)
+	var signer gossh.Signer
+	if len(passphrase) == 0 {
+		signer, err = gossh.ParsePrivateKey(data)
+	} else {
+		signer, err = gossh.ParsePrivateKeyWithPassphrase(data, passphrase)
+	}
+	if err != nil {
+		return "", err
+	}
+	return gossh.FingerprintSHA256(signer.PublicKey()), nil
+}
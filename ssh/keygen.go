@@ -0,0 +1,117 @@
+package ssh
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"tssh/models"
+
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// GenerateKey 生成新的密钥对, 返回的 PrivateKey 为明文 PEM
+func GenerateKey(name string, keyType string) (models.SSHKey, error) {
+	var priv crypto.Signer
+	var err error
+	switch keyType {
+	case models.KeyTypeEd25519:
+		_, priv, err = ed25519.GenerateKey(rand.Reader)
+	case models.KeyTypeRSA:
+		priv, err = rsa.GenerateKey(rand.Reader, 4096)
+	case models.KeyTypeECDSA:
+		priv, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		return models.SSHKey{}, fmt.Errorf("unsupported key type: %s", keyType)
+	}
+	if err != nil {
+		return models.SSHKey{}, err
+	}
+
+	block, err := gossh.MarshalPrivateKey(priv, name)
+	if err != nil {
+		return models.SSHKey{}, err
+	}
+	pub, err := gossh.NewPublicKey(priv.Public())
+	if err != nil {
+		return models.SSHKey{}, err
+	}
+	return models.SSHKey{
+		Name:        name,
+		KeyType:     keyType,
+		PrivateKey:  string(pem.EncodeToMemory(block)),
+		PublicKey:   authorizedKey(pub, name),
+		Fingerprint: gossh.FingerprintSHA256(pub),
+	}, nil
+}
+
+func authorizedKey(pub gossh.PublicKey, comment string) string {
+	line := strings.TrimSpace(string(gossh.MarshalAuthorizedKey(pub)))
+	if comment != "" {
+		line += " " + comment
+	}
+	return line
+}
+
+// LocalKeys 列出 ~/.ssh 目录下成对存在的密钥文件
+func LocalKeys() []models.SSHKey {
+	dir := ExpandPath("~/.ssh")
+	pubFiles, err := filepath.Glob(filepath.Join(dir, "*.pub"))
+	if err != nil {
+		return nil
+	}
+	var keys []models.SSHKey
+	for _, pubFile := range pubFiles {
+		privFile := strings.TrimSuffix(pubFile, ".pub")
+		if _, err := os.Stat(privFile); err != nil {
+			continue
+		}
+		data, err := os.ReadFile(pubFile)
+		if err != nil {
+			continue
+		}
+		pub, comment, _, _, err := gossh.ParseAuthorizedKey(data)
+		if err != nil {
+			continue
+		}
+		keys = append(keys, models.SSHKey{
+			Name:        filepath.Base(privFile),
+			KeyType:     keyTypeName(pub.Type()),
+			PublicKey:   authorizedKey(pub, comment),
+			Fingerprint: gossh.FingerprintSHA256(pub),
+			Path:        privFile,
+		})
+	}
+	return keys
+}
+
+func keyTypeName(sshType string) string {
+	switch {
+	case sshType == gossh.KeyAlgoED25519:
+		return models.KeyTypeEd25519
+	case sshType == gossh.KeyAlgoRSA:
+		return models.KeyTypeRSA
+	case strings.HasPrefix(sshType, "ecdsa-"):
+		return models.KeyTypeECDSA
+	}
+	return sshType
+}
+
+// keyPrivateData 返回密钥的明文私钥内容
+func keyPrivateData(key models.SSHKey) ([]byte, error) {
+	if key.Path != "" {
+		return os.ReadFile(key.Path)
+	}
+	data, err := models.DecryptString(key.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt private key: %w", err)
+	}
+	return []byte(data), nil
+}
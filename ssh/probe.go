@@ -0,0 +1,81 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// 主机密钥与 ~/.ssh/known_hosts 比较的结果
+const (
+	HostKeyKnown   = "known"
+	HostKeyUnknown = "unknown"
+	HostKeyChanged = "changed"
+)
+
+// HostInfo 探测主机得到的信息
+type HostInfo struct {
+	// Latency 建立 TCP 连接的耗时, 为 0 表示无法连接
+	Latency     time.Duration
+	KeyType     string
+	Fingerprint string
+	// KeyStatus 主机密钥是否在 known_hosts 中, known_hosts 不存在时为空
+	KeyStatus string
+}
+
+// errProbeDone 取得主机密钥后中止握手, 探测不进行认证
+var errProbeDone = errors.New("probe done")
+
+// ProbeHost 连接主机并读取其主机密钥, 不进行认证
+// 能够建立 TCP 连接但 SSH 握手失败时同时返回 Latency 和错误
+func ProbeHost(host string, port int, timeout time.Duration) (HostInfo, error) {
+	var info HostInfo
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	start := time.Now()
+	c, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return info, err
+	}
+	defer c.Close()
+	info.Latency = max(time.Since(start), time.Microsecond)
+	c.SetDeadline(time.Now().Add(timeout))
+
+	var hostKey gossh.PublicKey
+	config := &gossh.ClientConfig{
+		User: "tssh",
+		HostKeyCallback: func(_ string, _ net.Addr, key gossh.PublicKey) error {
+			hostKey = key
+			return errProbeDone
+		},
+		Timeout: timeout,
+	}
+	_, _, _, err = gossh.NewClientConn(c, addr, config)
+	if hostKey == nil {
+		return info, fmt.Errorf("ssh handshake: %w", err)
+	}
+	info.KeyType = hostKey.Type()
+	info.Fingerprint = gossh.FingerprintSHA256(hostKey)
+	info.KeyStatus = knownHostStatus(addr, c.RemoteAddr(), hostKey)
+	return info, nil
+}
+
+func knownHostStatus(addr string, remote net.Addr, key gossh.PublicKey) string {
+	callback, err := knownhosts.New(ExpandPath("~/.ssh/known_hosts"))
+	if err != nil {
+		return ""
+	}
+	err = callback(addr, remote, key)
+	var keyErr *knownhosts.KeyError
+	switch {
+	case err == nil:
+		return HostKeyKnown
+	case errors.As(err, &keyErr) && len(keyErr.Want) > 0:
+		return HostKeyChanged
+	}
+	return HostKeyUnknown
+}
@@ -0,0 +1,105 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"tssh/models"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// credentialOption 连接时可选的一组凭据, identityID 为 0 表示使用连接默认的凭据
+type credentialOption struct {
+	label      string
+	identityID int64
+}
+
+// chooserModel 主机配置了多个登录身份时, 连接前选择使用的身份
+type chooserModel struct {
+	options   []credentialOption
+	cursor    int
+	mainModel *MainModel
+	conn      models.ConnInfo
+	command   models.RunCommand
+	keyMap    *ChooserKeyMap
+}
+
+func newChooserModel(mainModel *MainModel, conn models.ConnInfo, command models.RunCommand, identities []models.Identity) chooserModel {
+	byID := make(map[int64]models.Identity)
+	for _, identity := range identities {
+		byID[identity.ID] = identity
+	}
+	defaultLabel := conn.Username + " (default)"
+	if identity, ok := byID[conn.IdentityID]; ok {
+		defaultLabel = fmt.Sprintf("%s (%s, default)", identity.Username, identity.Name)
+	}
+	options := []credentialOption{{label: defaultLabel}}
+	for _, id := range conn.ExtraIdentityIDs {
+		identity, ok := byID[id]
+		if !ok || id == conn.IdentityID {
+			continue
+		}
+		options = append(options, credentialOption{
+			label:      fmt.Sprintf("%s (%s)", identity.Username, identity.Name),
+			identityID: id,
+		})
+	}
+	return chooserModel{
+		options:   options,
+		mainModel: mainModel,
+		conn:      conn,
+		command:   command,
+		keyMap:    NewChooserKeyMap(),
+	}
+}
+
+func (m chooserModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m chooserModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+		case key.Matches(msg, m.keyMap.Back):
+			return m.mainModel, nil
+		case key.Matches(msg, m.keyMap.Up):
+			m.cursor = (m.cursor - 1 + len(m.options)) % len(m.options)
+		case key.Matches(msg, m.keyMap.Down):
+			m.cursor = (m.cursor + 1) % len(m.options)
+		case key.Matches(msg, m.keyMap.Select):
+			return m.choose(m.cursor)
+		}
+		// 数字键直接选择对应的凭据
+		if s := msg.String(); len(s) == 1 && s[0] >= '1' && s[0] <= '9' {
+			if idx := int(s[0] - '1'); idx < len(m.options) {
+				return m.choose(idx)
+			}
+		}
+	}
+	return m, nil
+}
+
+// choose 使用选中的凭据连接主机
+func (m chooserModel) choose(idx int) (tea.Model, tea.Cmd) {
+	conn := m.conn
+	if id := m.options[idx].identityID; id != 0 {
+		conn.IdentityID = id
+	}
+	m.mainModel.WillConn = &models.RunContext{Context: &conn, Command: m.command}
+	return m.mainModel, tea.Quit
+}
+
+func (m chooserModel) View() string {
+	var b strings.Builder
+	b.WriteString(questionStyle.Render(fmt.Sprintf("Connect to %s as:", m.conn.Name)) + "\n")
+	for i, option := range m.options {
+		line := fmt.Sprintf("%d. %s", i+1, option.label)
+		if i == m.cursor {
+			b.WriteString(focusedStyle.Render("> "+line) + "\n")
+		} else {
+			b.WriteString(noStyle.Render("  "+line) + "\n")
+		}
+	}
+	return dialogBoxStyle.Render(strings.TrimSuffix(b.String(), "\n"))
+}
@@ -0,0 +1,261 @@
+package ui
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"tssh/models"
+)
+
+// connColumn 连接列表中可以选择显示的列
+type connColumn struct {
+	column
+	name string
+	// value 单元格的内容
+	value func(m *MainModel, conn *models.ConnInfo) string
+	// compare 排序时比较两个连接, 为 nil 时按单元格内容比较 (忽略大小写)
+	compare func(m *MainModel, a, b *models.ConnInfo) int
+}
+
+// metaColumnPrefix 自定义信息列的前缀, 如 meta.owner 显示自定义信息中 owner 的值
+const metaColumnPrefix = "meta."
+
+// defaultColumns 未配置 ui.columns 时显示的列
+var defaultColumns = []string{"id", "name", "host", "port", "username", "source"}
+
+// builtinColumns 所有内置的列, 终端较窄时按 Hide 从大到小依次隐藏
+var builtinColumns = []connColumn{
+	{
+		name:   "id",
+		column: column{Title: "ID", Width: 4, Hide: 2},
+		value: func(_ *MainModel, conn *models.ConnInfo) string {
+			if conn.Source != "" {
+				// 共享连接的 ID 仅在本次运行中有效, 不显示
+				return ""
+			}
+			return fmt.Sprintf("%d", conn.ID)
+		},
+		compare: func(_ *MainModel, a, b *models.ConnInfo) int {
+			return cmp.Compare(a.ID, b.ID)
+		},
+	},
+	{
+		name:   "name",
+		column: column{Title: "Name", Width: 15, Flex: 2},
+		value:  func(_ *MainModel, conn *models.ConnInfo) string { return conn.Name },
+	},
+	{
+		name:   "host",
+		column: column{Title: "Host", Width: 15, Flex: 3},
+		value:  func(_ *MainModel, conn *models.ConnInfo) string { return conn.Host },
+	},
+	{
+		name:   "port",
+		column: column{Title: "Port", Width: 6, Hide: 3},
+		value:  func(_ *MainModel, conn *models.ConnInfo) string { return fmt.Sprintf("%d", conn.Port) },
+		compare: func(_ *MainModel, a, b *models.ConnInfo) int {
+			return cmp.Compare(a.Port, b.Port)
+		},
+	},
+	{
+		name:   "username",
+		column: column{Title: "Username", Width: 10, Flex: 1, Hide: 1},
+		value:  func(_ *MainModel, conn *models.ConnInfo) string { return conn.Username },
+	},
+	{
+		name:   "source",
+		column: column{Title: "Source", Width: 10, Hide: 4},
+		value:  func(_ *MainModel, conn *models.ConnInfo) string { return conn.Source },
+	},
+	{
+		name:   "last_used",
+		column: column{Title: "Last Used", Width: 16, Hide: 5},
+		value: func(_ *MainModel, conn *models.ConnInfo) string {
+			if conn.LastUsed.IsZero() {
+				return ""
+			}
+			return conn.LastUsed.Local().Format("2006-01-02 15:04")
+		},
+		compare: func(_ *MainModel, a, b *models.ConnInfo) int {
+			return a.LastUsed.Compare(b.LastUsed)
+		},
+	},
+	{
+		name:   "status",
+		column: column{Title: "Status", Width: 8, Hide: 5},
+		value: func(m *MainModel, conn *models.ConnInfo) string {
+			probe := m.probes[probeAddr(conn)]
+			switch {
+			case probe == nil:
+				return ""
+			case !probe.done:
+				return "…"
+			case probe.info.Latency == 0:
+				return "down"
+			}
+			return latency(probe.info.Latency)
+		},
+		compare: func(m *MainModel, a, b *models.ConnInfo) int {
+			return cmp.Compare(m.statusRank(a), m.statusRank(b))
+		},
+	},
+	{
+		name:   "notes",
+		column: column{Title: "Notes", Width: 20, Flex: 2, Hide: 6},
+		value:  func(_ *MainModel, conn *models.ConnInfo) string { return notesSnippet(conn.Notes) },
+	},
+}
+
+// lookupColumn 根据列名返回列的定义
+func lookupColumn(name string) (connColumn, bool) {
+	for _, c := range builtinColumns {
+		if c.name == name {
+			return c, true
+		}
+	}
+	key, ok := strings.CutPrefix(name, metaColumnPrefix)
+	if !ok || key == "" {
+		return connColumn{}, false
+	}
+	return connColumn{
+		name:   name,
+		column: column{Title: key, Width: 10, Flex: 1, Hide: 6},
+		value:  func(_ *MainModel, conn *models.ConnInfo) string { return conn.Meta[key] },
+	}, true
+}
+
+// CheckColumns 检查配置中的列名和排序列
+func CheckColumns(columns []string, sort []string) error {
+	seen := make(map[string]bool)
+	for _, name := range columns {
+		if _, ok := lookupColumn(name); !ok {
+			return fmt.Errorf("ui.columns: unknown column %q, available: %s", name, strings.Join(columnNames(), ", "))
+		}
+		if seen[name] {
+			return fmt.Errorf("ui.columns: duplicate column %q", name)
+		}
+		seen[name] = true
+	}
+	seen = make(map[string]bool)
+	for _, spec := range sort {
+		name, _ := parseSort(spec)
+		if _, ok := lookupColumn(name); !ok {
+			return fmt.Errorf("ui.sort: unknown column %q", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("ui.sort: duplicate column %q", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+func columnNames() []string {
+	names := make([]string, 0, len(builtinColumns)+1)
+	for _, c := range builtinColumns {
+		names = append(names, c.name)
+	}
+	return append(names, metaColumnPrefix+"<key>")
+}
+
+// parseSort 解析排序列, "-name" 表示按 name 降序
+func parseSort(spec string) (name string, desc bool) {
+	if name, ok := strings.CutPrefix(spec, "-"); ok {
+		return name, true
+	}
+	return spec, false
+}
+
+func sortSpec(name string, desc bool) string {
+	if desc {
+		return "-" + name
+	}
+	return name
+}
+
+// sortConns 按排序列依次比较连接, 比较结果相同时保持存储中的顺序
+func (m *MainModel) sortConns(conns []*models.ConnInfo) {
+	if len(m.sort) == 0 {
+		return
+	}
+	type key struct {
+		col  connColumn
+		desc bool
+	}
+	var keys []key
+	for _, spec := range m.sort {
+		name, desc := parseSort(spec)
+		if col, ok := lookupColumn(name); ok {
+			keys = append(keys, key{col, desc})
+		}
+	}
+	slices.SortStableFunc(conns, func(a, b *models.ConnInfo) int {
+		for _, k := range keys {
+			var c int
+			if k.col.compare != nil {
+				c = k.col.compare(m, a, b)
+			} else {
+				c = cmp.Compare(strings.ToLower(k.col.value(m, a)), strings.ToLower(k.col.value(m, b)))
+			}
+			if k.desc {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+}
+
+// sortsBy 是否按该列排序
+func (m *MainModel) sortsBy(name string) bool {
+	return slices.ContainsFunc(m.sort, func(spec string) bool {
+		n, _ := parseSort(spec)
+		return n == name
+	})
+}
+
+// sortMark 在排序列的标题后显示排序方向, 次要的排序列同时显示其顺序
+func (m *MainModel) sortMark(name string) string {
+	for i, spec := range m.sort {
+		n, desc := parseSort(spec)
+		if n != name {
+			continue
+		}
+		mark := " ↑"
+		if desc {
+			mark = " ↓"
+		}
+		if i > 0 {
+			mark += fmt.Sprint(i + 1)
+		}
+		return mark
+	}
+	return ""
+}
+
+// statusRank 按状态排序时可达的主机按延迟在前, 其次为不可达和未探测的主机
+func (m *MainModel) statusRank(conn *models.ConnInfo) time.Duration {
+	probe := m.probes[probeAddr(conn)]
+	switch {
+	case probe == nil || !probe.done:
+		return 1<<63 - 1
+	case probe.info.Latency == 0:
+		return 1<<63 - 2
+	}
+	return probe.info.Latency
+}
+
+// notesSnippet 返回备注中第一行有内容的文本, 去掉 Markdown 的标题和列表标记
+func notesSnippet(notes string) string {
+	for _, line := range strings.Split(notes, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "#>-*+ "))
+		if line != "" && !strings.HasPrefix(line, "```") {
+			return line
+		}
+	}
+	return ""
+}
@@ -0,0 +1,180 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// columnItem 列设置界面中的一列
+type columnItem struct {
+	col   connColumn
+	shown bool
+}
+
+// columnsModel 选择连接列表显示的列、调整顺序和排序方式, 保存后写入配置文件
+type columnsModel struct {
+	items     []columnItem
+	sort      []string
+	cursor    int
+	mainModel *MainModel
+	keyMap    *ColumnsKeyMap
+	err       error
+}
+
+func newColumnsModel(mainModel *MainModel) *columnsModel {
+	m := &columnsModel{
+		mainModel: mainModel,
+		sort:      slices.Clone(mainModel.sort),
+		keyMap:    NewColumnsKeyMap(),
+	}
+	// 先列出显示的列, 再列出其他内置的列和连接中出现过的自定义信息
+	shown := make(map[string]bool)
+	for _, c := range mainModel.columns {
+		m.items = append(m.items, columnItem{col: c, shown: true})
+		shown[c.name] = true
+	}
+	names := make([]string, 0, len(builtinColumns))
+	for _, c := range builtinColumns {
+		names = append(names, c.name)
+	}
+	var metaNames []string
+	for _, conn := range mainModel.connections {
+		for _, k := range conn.MetaKeys() {
+			metaNames = append(metaNames, metaColumnPrefix+k)
+		}
+	}
+	slices.Sort(metaNames)
+	for _, name := range append(names, slices.Compact(metaNames)...) {
+		if shown[name] {
+			continue
+		}
+		c, _ := lookupColumn(name)
+		m.items = append(m.items, columnItem{col: c})
+	}
+	return m
+}
+
+func (m *columnsModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *columnsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		// 主界面也需要知道窗口大小
+		m.mainModel.Update(msg)
+	case tea.KeyMsg:
+		m.err = nil
+		switch {
+		case key.Matches(msg, m.keyMap.Back):
+			return m.mainModel, nil
+		case key.Matches(msg, m.keyMap.Up):
+			m.cursor = max(m.cursor-1, 0)
+		case key.Matches(msg, m.keyMap.Down):
+			m.cursor = min(m.cursor+1, len(m.items)-1)
+		case key.Matches(msg, m.keyMap.Toggle):
+			m.items[m.cursor].shown = !m.items[m.cursor].shown
+		case key.Matches(msg, m.keyMap.MoveUp):
+			if m.cursor > 0 {
+				m.items[m.cursor-1], m.items[m.cursor] = m.items[m.cursor], m.items[m.cursor-1]
+				m.cursor--
+			}
+		case key.Matches(msg, m.keyMap.MoveDown):
+			if m.cursor < len(m.items)-1 {
+				m.items[m.cursor+1], m.items[m.cursor] = m.items[m.cursor], m.items[m.cursor+1]
+				m.cursor++
+			}
+		case key.Matches(msg, m.keyMap.Sort):
+			m.cycleSort(m.items[m.cursor].col.name)
+		case key.Matches(msg, m.keyMap.Save):
+			if err := m.save(); err != nil {
+				m.err = err
+				return m, nil
+			}
+			return m.mainModel, nil
+		}
+	}
+	return m, nil
+}
+
+// cycleSort 依次切换该列为升序、降序和不排序, 新增的排序列优先级最低
+func (m *columnsModel) cycleSort(name string) {
+	for i, spec := range m.sort {
+		n, desc := parseSort(spec)
+		if n != name {
+			continue
+		}
+		if desc {
+			m.sort = slices.Delete(m.sort, i, i+1)
+		} else {
+			m.sort[i] = sortSpec(name, true)
+		}
+		return
+	}
+	m.sort = append(m.sort, name)
+}
+
+// save 应用到主界面并保存到配置文件
+func (m *columnsModel) save() error {
+	var names []string
+	for _, item := range m.items {
+		if item.shown {
+			names = append(names, item.col.name)
+		}
+	}
+	if len(names) == 0 {
+		return errors.New("at least one column must be shown")
+	}
+	main := m.mainModel
+	main.setColumns(names)
+	// 与默认的列相同时不写入配置, 以便使用今后版本的默认值
+	if slices.Equal(names, defaultColumns) {
+		names = nil
+	}
+	main.cfg.UI.Columns = names
+	main.setSort(m.sort)
+	return main.saveSort("ui.columns")
+}
+
+func (m *columnsModel) View() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("Columns") + "\n\n")
+	for i, item := range m.items {
+		check := "[ ] "
+		if item.shown {
+			check = "[x] "
+		}
+		line := check + fmt.Sprintf("%-12s", item.col.Title) + m.sortMark(item.col.name)
+		if i == m.cursor {
+			b.WriteString(focusedStyle.Render("> "+line) + "\n")
+		} else {
+			b.WriteString("  " + line + "\n")
+		}
+	}
+	b.WriteString("\n")
+	if m.err != nil {
+		b.WriteString(errorStyle.Render(m.err.Error()))
+	}
+	b.WriteString("\n" + helpStyle.Render(helpStr(m.keyMap)) + "\n")
+	return b.String()
+}
+
+// sortMark 显示该列的排序方向和顺序
+func (m *columnsModel) sortMark(name string) string {
+	for i, spec := range m.sort {
+		n, desc := parseSort(spec)
+		if n != name {
+			continue
+		}
+		if desc {
+			return fmt.Sprintf("↓ sort %d", i+1)
+		}
+		return fmt.Sprintf("↑ sort %d", i+1)
+	}
+	return ""
+}
@@ -0,0 +1,319 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"tssh/database"
+	"tssh/models"
+	"tssh/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mattn/go-runewidth"
+)
+
+const (
+	// probeTimeout 探测主机的超时时间, 结果超过两倍该时间仍未返回 (例如期间打开了子界面) 时重新探测
+	probeTimeout = 3 * time.Second
+	// detailWidth 右侧详情面板的宽度 (包括边框)
+	detailWidth = 50
+	// sideDetailMinWidth 终端宽度不小于该值时详情面板显示在表格右侧, 否则显示在表格下方
+	sideDetailMinWidth = 120
+	// detailHeight 下方详情面板的行数 (不包括边框)
+	detailHeight = 12
+	// detailLabelWidth 详情面板中字段名的宽度
+	detailLabelWidth = 11
+)
+
+// hostProbe 主机的可达性和主机密钥, 按 host:port 缓存
+type hostProbe struct {
+	started time.Time
+	done    bool
+	info    ssh.HostInfo
+	err     error
+}
+
+// connDetail 需要读取存储或私钥文件才能得到的连接信息, 按 detailKey 缓存
+type connDetail struct {
+	started time.Time
+	done    bool
+	// resolved 应用了身份凭据的连接
+	resolved models.ConnInfo
+	identity string
+	keyFP    string
+	err      error
+}
+
+type probeMsg struct {
+	addr  string
+	probe hostProbe
+}
+
+type connDetailMsg struct {
+	key    string
+	detail connDetail
+}
+
+// detailKey 连接修改后需要重新读取详情
+func detailKey(conn *models.ConnInfo) string {
+	return fmt.Sprintf("%s/%d/%d", conn.Source, conn.ID, conn.Version)
+}
+
+func probeAddr(conn *models.ConnInfo) string {
+	return fmt.Sprintf("%s:%d", conn.Host, conn.Port)
+}
+
+// stale 请求的结果没有返回且已超时
+func stale(started time.Time, done bool) bool {
+	return !done && time.Since(started) > 2*probeTimeout
+}
+
+// detailCmd 为选中的连接读取详情面板需要的信息, 已缓存的不再重复读取
+func (m *MainModel) detailCmd() tea.Cmd {
+	conn := m.Cursor()
+	if !m.showDetails || conn == nil {
+		return nil
+	}
+	var cmds []tea.Cmd
+	if k := detailKey(conn); m.details[k] == nil || stale(m.details[k].started, m.details[k].done) {
+		m.details[k] = &connDetail{started: time.Now()}
+		store, c, defaultKey := m.db, *conn, m.cfg.Connect.DefaultKey
+		cmds = append(cmds, func() tea.Msg {
+			return connDetailMsg{k, loadConnDetail(store, c, defaultKey)}
+		})
+	}
+	cmds = append(cmds, m.probeCmd(conn))
+	return tea.Batch(cmds...)
+}
+
+// statusCmd 显示状态列时探测所有过滤后的连接
+func (m *MainModel) statusCmd() tea.Cmd {
+	if !m.showsColumn("status") {
+		return nil
+	}
+	var cmds []tea.Cmd
+	for _, conn := range m.currentItems {
+		cmds = append(cmds, m.probeCmd(conn))
+	}
+	return tea.Batch(cmds...)
+}
+
+// probeCmd 在后台探测连接的主机, 已有结果或正在探测时返回 nil
+func (m *MainModel) probeCmd(conn *models.ConnInfo) tea.Cmd {
+	addr := probeAddr(conn)
+	if !m.cfg.UI.ProbeHosts || m.probes[addr] != nil && !stale(m.probes[addr].started, m.probes[addr].done) {
+		return nil
+	}
+	m.probes[addr] = &hostProbe{started: time.Now()}
+	host, port := conn.Host, conn.Port
+	return func() tea.Msg {
+		info, err := ssh.ProbeHost(host, port, probeTimeout)
+		return probeMsg{addr, hostProbe{done: true, info: info, err: err}}
+	}
+}
+
+// loadConnDetail 在后台读取连接引用的身份和私钥指纹
+func loadConnDetail(store database.Store, conn models.ConnInfo, defaultKey string) connDetail {
+	d := connDetail{done: true}
+	if conn.IdentityID != 0 {
+		identities, ok := database.As[database.IdentityStore](store)
+		if !ok {
+			d.err = errors.New("store does not support identities")
+			return d
+		}
+		identity, err := identities.GetIdentity(conn.IdentityID)
+		if err != nil {
+			d.err = fmt.Errorf("failed to load identity: %w", err)
+			return d
+		}
+		d.identity = identity.Name
+		conn.ApplyIdentity(identity)
+	}
+	d.resolved = conn
+	if conn.AuthType == models.UseKey {
+		d.keyFP, d.err = ssh.KeyFingerprint(&conn, defaultKey)
+	}
+	return d
+}
+
+// sideDetails 详情面板是否显示在表格右侧
+func (m *MainModel) sideDetails() bool {
+	return m.width >= sideDetailMinWidth
+}
+
+// detailRow 详情面板中的一项, warn 为 true 时以错误样式显示
+type detailRow struct {
+	label, value string
+	warn         bool
+}
+
+// detailView 渲染选中连接的详情面板, width 和 height 包括边框
+func (m *MainModel) detailView(width, height int) string {
+	inner := width - tableBorder - 2
+	var lines []string
+	conn := m.Cursor()
+	if conn == nil {
+		lines = append(lines, noStyle.Render("No connection selected"))
+	} else {
+		lines = append(lines, titleStyle.Render(truncate(conn.Name, inner)))
+	}
+	indent := strings.Repeat(" ", detailLabelWidth)
+	for _, row := range m.detailRows(conn) {
+		for i, part := range wrapText(row.value, inner-detailLabelWidth) {
+			if row.warn {
+				part = errorStyle.Render(part)
+			}
+			if i == 0 {
+				lines = append(lines, noStyle.Render(runewidth.FillRight(row.label, detailLabelWidth))+part)
+			} else {
+				lines = append(lines, indent+part)
+			}
+		}
+	}
+	if conn != nil && conn.Notes != "" {
+		lines = append(lines, "", renderMarkdown(conn.Notes, inner))
+	}
+	return detailStyle.
+		Width(width - tableBorder).
+		Height(height - tableBorder).
+		MaxHeight(height).
+		Render(strings.Join(lines, "\n"))
+}
+
+// detailRows 依次列出连接的各项信息
+func (m *MainModel) detailRows(conn *models.ConnInfo) []detailRow {
+	if conn == nil {
+		return nil
+	}
+	var rows []detailRow
+	field := func(label, value string) {
+		rows = append(rows, detailRow{label: label, value: value})
+	}
+	detail := m.details[detailKey(conn)]
+	resolved := conn
+	if detail != nil && detail.done && detail.err == nil {
+		resolved = &detail.resolved
+	}
+
+	field("Host", fmt.Sprintf("%s@%s:%d", resolved.Username, conn.Host, conn.Port))
+	if conn.Source != "" {
+		field("Source", conn.Source)
+	}
+	if conn.IdentityID != 0 {
+		name := fmt.Sprintf("#%d", conn.IdentityID)
+		if detail != nil && detail.identity != "" {
+			name = detail.identity
+		}
+		field("Identity", name)
+	}
+	switch resolved.AuthType {
+	case models.UsePass:
+		field("Auth", "password")
+		switch resolved.SecretBackend {
+		case models.SecretBackendLocal:
+			field("Password", "stored in tssh")
+		default:
+			field("Password", fmt.Sprintf("%s: %s", resolved.SecretBackend, resolved.SecretRef))
+		}
+	case models.UseKey:
+		field("Auth", "key")
+		switch {
+		case resolved.KeyData != "":
+			field("Key", "imported into tssh")
+		case strings.TrimSpace(resolved.PrivateKey) == "":
+			field("Key", m.cfg.Connect.DefaultKey+" (default)")
+		default:
+			field("Key", resolved.PrivateKey)
+		}
+		switch {
+		case detail == nil || !detail.done:
+			field("Key FP", "reading…")
+		case detail.err != nil:
+			rows = append(rows, detailRow{label: "Key FP", value: detail.err.Error(), warn: true})
+		default:
+			field("Key FP", detail.keyFP)
+		}
+	}
+	if detail != nil && detail.err != nil && resolved.AuthType != models.UseKey {
+		rows = append(rows, detailRow{label: "Error", value: detail.err.Error(), warn: true})
+	}
+	if conn.TOTPSecret != "" {
+		field("TOTP", "enabled")
+	}
+	if n := len(conn.ExtraIdentityIDs); n > 0 {
+		field("Also as", fmt.Sprintf("%d more identities", n))
+	}
+	for _, k := range conn.MetaKeys() {
+		field(truncate(k, detailLabelWidth-1), conn.Meta[k])
+	}
+	if conn.LastUsed.IsZero() {
+		field("Last used", "never")
+	} else {
+		field("Last used", conn.LastUsed.Local().Format("2006-01-02 15:04")+" ("+ago(time.Since(conn.LastUsed))+")")
+	}
+
+	if !m.cfg.UI.ProbeHosts {
+		return rows
+	}
+	probe := m.probes[probeAddr(conn)]
+	switch {
+	case probe == nil || !probe.done:
+		field("Reachable", "checking…")
+	case probe.info.Latency == 0:
+		rows = append(rows, detailRow{label: "Reachable", value: "no: " + probe.err.Error(), warn: true})
+	case probe.err != nil:
+		field("Reachable", fmt.Sprintf("port open (%s), %s", latency(probe.info.Latency), probe.err))
+	default:
+		field("Reachable", fmt.Sprintf("yes (%s)", latency(probe.info.Latency)))
+		field("Host key", probe.info.KeyType+" "+probe.info.Fingerprint)
+		switch probe.info.KeyStatus {
+		case ssh.HostKeyKnown:
+			field("", "matches known_hosts")
+		case ssh.HostKeyUnknown:
+			field("", "not in known_hosts")
+		case ssh.HostKeyChanged:
+			rows = append(rows, detailRow{value: "differs from known_hosts!", warn: true})
+		}
+	}
+	return rows
+}
+
+func latency(d time.Duration) string {
+	if d < time.Millisecond {
+		return "<1ms"
+	}
+	return d.Round(time.Millisecond).String()
+}
+
+// ago 以粗略的单位显示时长
+func ago(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+}
+
+// wrapText 按显示宽度将文本折成多行
+func wrapText(s string, width int) []string {
+	if width <= 0 || runewidth.StringWidth(s) <= width {
+		return []string{s}
+	}
+	var lines []string
+	line, w := "", 0
+	for _, r := range s {
+		rw := runewidth.RuneWidth(r)
+		if w+rw > width {
+			lines = append(lines, line)
+			line, w = "", 0
+		}
+		line += string(r)
+		w += rw
+	}
+	return append(lines, line)
+}
@@ -3,6 +3,8 @@ package ui
 import (
 	"tssh/models"
 
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -19,15 +21,22 @@ type DeleteConfirmMsg struct {
 type confirmModel struct {
 	focusIndex int
 	question   string
-	mainModel  tea.Model
+	mainModel  tea.Model // 关闭对话框后返回的界面
+	confirmed  tea.Msg   // 确认时发送给 mainModel 的消息
+	keyMap     *DialogKeyMap
+	help       help.Model
+	showHelp   bool // 显示完整的按键帮助
 }
 
-func newConfirmModel(mainModel tea.Model, question string) confirmModel {
+func newConfirmModel(mainModel tea.Model, question string, confirmed tea.Msg) confirmModel {
 
 	return confirmModel{
 		focusIndex: idxDialogNo,
 		question:   question,
 		mainModel:  mainModel,
+		confirmed:  confirmed,
+		keyMap:     NewDialogKeyMap(),
+		help:       newHelp(),
 	}
 }
 
@@ -36,35 +45,53 @@ func (m confirmModel) Init() tea.Cmd {
 }
 func (m confirmModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
-	case tea.KeyMsg:
-		switch msg.String() {
-		case "n", "esc", "y", "q", "enter":
-			if msg.String() == "y" || (msg.String() == "enter" && m.focusIndex == idxDialogYes) {
-				return m.mainModel, func() tea.Msg {
-					return DeleteConfirmMsg{m.mainModel.(*MainModel).Cursor()}
-				}
+	case tea.MouseMsg:
+		if leftClick(msg) {
+			if button := m.buttonAt(msg.X, msg.Y); button >= 0 {
+				return m.answer(button == idxDialogYes)
 			}
-			return m.mainModel, nil
-		case "tab", "shift+tab", "h", "l":
+		}
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keyMap.Yes, m.keyMap.No, m.keyMap.Confirm):
+			return m.answer(key.Matches(msg, m.keyMap.Yes) || (key.Matches(msg, m.keyMap.Confirm) && m.focusIndex == idxDialogYes))
+		case key.Matches(msg, m.keyMap.Switch):
 			m.focusIndex = (m.focusIndex + 1) % 2
+		case key.Matches(msg, m.keyMap.Help):
+			m.showHelp = !m.showHelp
 		}
 	}
 	return m, nil
 }
-func (m confirmModel) View() string {
-	question := questionStyle.Render(m.question)
 
-	var yesButton, noButton string
+// answer 关闭对话框, 确认时向返回的界面发送 confirmed
+func (m confirmModel) answer(yes bool) (tea.Model, tea.Cmd) {
+	if !yes {
+		return m.mainModel, nil
+	}
+	return m.mainModel, func() tea.Msg {
+		return m.confirmed
+	}
+}
+
+// buttons 返回渲染后的确认和取消按钮, 焦点所在的按钮高亮显示
+func (m confirmModel) buttons() (string, string) {
 	if m.focusIndex == idxDialogYes {
-		yesButton = focusedButtonStyle.Render("Yes")
-		noButton = buttonStyle.Render("No")
-	} else {
-		yesButton = buttonStyle.Render("Yes")
-		noButton = focusedButtonStyle.Render(" No")
+		return focusedButtonStyle.Render("Yes"), buttonStyle.Render("No")
 	}
+	return buttonStyle.Render("Yes"), focusedButtonStyle.Render(" No")
+}
+
+func (m confirmModel) View() string {
+	question := questionStyle.Render(m.question)
+	yesButton, noButton := m.buttons()
 	buttons := lipgloss.JoinHorizontal(lipgloss.Top, yesButton, " ", noButton)
 
 	dialogContent := lipgloss.JoinVertical(lipgloss.Center, question, buttons)
 	ui := dialogBoxStyle.Render(dialogContent)
-	return ui
+	// 帮助显示在对话框下方
+	if m.showHelp {
+		return ui + "\n\n" + fullHelpView(m.help, m.keyMap.FullHelp(), 0)
+	}
+	return ui + "\n\n" + m.help.ShortHelpView(m.keyMap.ShortHelp())
 }
@@ -1,54 +1,103 @@
 package ui
 
 import (
+	"errors"
 	"strconv"
 	"strings"
 	"tssh/database"
 	"tssh/models"
+	"tssh/ssh"
 
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 	"github.com/go-playground/validator/v10"
 )
 
-const (
-	keyCharH tea.KeyType = 'h'
-	keyCharL tea.KeyType = 'l'
-)
-
 const (
 	idxName = iota
 	idxHost
 	idxPort
+	idxIdentity
+	idxExtraIdentities
 	idxUsername
 	idxAuthType
+	idxSecretBackend
 	idxPass
+	idxSecretRef
 	idxPrivateKey
+	idxPassphrase
+	idxImportKey
+	idxTOTP
 	idxEnter
 	idxCancel
+	// 以下为多行文本框, 保存在 areas 中
+	idxNotes
+	idxMeta
 )
 
 type formModel struct {
 	inputs           []textinput.Model
+	areas            []textarea.Model // 下标为 idx-idxNotes
 	title            string
 	focusIndex       int
 	authTypeSelected models.AuthType
+	importKey        bool
+	identities       []models.Identity
+	identitySelected int // 0 表示不使用身份, 其余为 identities 下标+1
+	extraSelected    map[int64]bool
+	extraCursor      int
+	secretBackend    int // models.SecretBackends 的下标
+	forIdentity      bool
 	mainModel        tea.Model
-	db               *database.DB
+	db               database.Store
+	identityStore    database.IdentityStore
+	overrideStore    database.OverrideStore // 不为 nil 时表单用于覆盖共享连接
 	conn             models.ConnInfo
 	err              error
 	isEdit           bool
+	keyMap           *FormKeyMap
+	help             help.Model
+	showHelp         bool // 显示完整的按键帮助
 }
 
-func newFormModel(mainModel tea.Model, db *database.DB, conn models.ConnInfo) formModel {
+func newFormModel(mainModel tea.Model, db database.Store, identityStore database.IdentityStore, conn models.ConnInfo) formModel {
 	m := formModel{
-		inputs:           make([]textinput.Model, 7),
+		inputs:           make([]textinput.Model, idxEnter),
 		focusIndex:       0,
 		authTypeSelected: conn.AuthType,
+		importKey:        conn.KeyData != "",
 		mainModel:        mainModel,
 		db:               db,
+		identityStore:    identityStore,
 		conn:             conn,
 		isEdit:           conn.ID != 0,
+		extraSelected:    make(map[int64]bool),
+		keyMap:           NewFormKeyMap(),
+		help:             newHelp(),
+	}
+	for i, backend := range models.SecretBackends {
+		if backend == conn.SecretBackend {
+			m.secretBackend = i
+		}
+	}
+	for _, id := range conn.ExtraIdentityIDs {
+		m.extraSelected[id] = true
+	}
+	if identityStore != nil {
+		identities, err := identityStore.GetAllIdentities()
+		if err != nil {
+			m.err = err
+		}
+		m.identities = identities
+	}
+	for i, identity := range m.identities {
+		if identity.ID == conn.IdentityID {
+			m.identitySelected = i + 1
+		}
 	}
 	if m.isEdit {
 		m.title = "Modify SSH Connection"
@@ -109,7 +158,19 @@ func newFormModel(mainModel tea.Model, db *database.DB, conn models.ConnInfo) fo
 	t.CharLimit = 30
 	m.inputs[idxPass] = t
 
-	// 输入框-密码
+	// 输入框-外部存储中的密码引用
+	t = textinput.New()
+	t.Placeholder = "pass show servers/web"
+	t.Prompt = "  Ref "
+	if m.isEdit {
+		t.SetValue(conn.SecretRef)
+	}
+	t.Width = 40
+	t.CharLimit = 500
+	m.inputs[idxSecretRef] = t
+	m.updateSecretRefPlaceholder()
+
+	// 输入框-私钥路径
 	t = textinput.New()
 	t.Placeholder = "PrivateKey"
 	t.Prompt = "  Key "
@@ -120,6 +181,89 @@ func newFormModel(mainModel tea.Model, db *database.DB, conn models.ConnInfo) fo
 	t.CharLimit = 5000
 	m.inputs[idxPrivateKey] = t
 
+	// 输入框-私钥密码
+	t = textinput.New()
+	t.Placeholder = "Don't anything when empty"
+	t.Prompt = "  Passphrase "
+	t.EchoMode = textinput.EchoPassword
+	t.Width = 30
+	t.CharLimit = 100
+	m.inputs[idxPassphrase] = t
+
+	// 输入框-TOTP 密钥
+	t = textinput.New()
+	t.Placeholder = "Base32 secret or otpauth:// uri"
+	if conn.TOTPSecret != "" {
+		t.Placeholder = "Don't anything when empty"
+	}
+	t.Prompt = "  TOTP "
+	t.EchoMode = textinput.EchoPassword
+	t.Width = 40
+	t.CharLimit = 500
+	m.inputs[idxTOTP] = t
+
+	m.areas = make([]textarea.Model, idxMeta-idxNotes+1)
+	// 多行文本框-Markdown 备注
+	a := textarea.New()
+	a.Placeholder = "Markdown notes"
+	a.ShowLineNumbers = false
+	a.CharLimit = 10000
+	a.SetWidth(60)
+	a.SetHeight(4)
+	a.SetValue(conn.Notes)
+	m.areas[idxNotes-idxNotes] = a
+
+	// 多行文本框-自定义信息, 每行一个 "键: 值"
+	a = textarea.New()
+	a.Placeholder = "owner: alice"
+	a.ShowLineNumbers = false
+	a.CharLimit = 2000
+	a.SetWidth(60)
+	a.SetHeight(3)
+	a.SetValue(strings.TrimSpace(models.FormatMeta(conn.Meta)))
+	m.areas[idxMeta-idxNotes] = a
+
+	return m
+}
+
+// newIdentityFormModel 创建新增或编辑共享身份的表单
+func newIdentityFormModel(parent tea.Model, identityStore database.IdentityStore, identity models.Identity) formModel {
+	conn := models.ConnInfo{ID: identity.ID, Name: identity.Name}
+	conn.ApplyIdentity(identity)
+	m := newFormModel(parent, nil, identityStore, conn)
+	m.forIdentity = true
+	m.identitySelected = 0
+	m.inputs[idxName].Placeholder = "Identity name"
+	if m.isEdit {
+		m.title = "Modify Identity"
+	} else {
+		m.title = "Add Identity"
+	}
+	return m
+}
+
+// newOverrideFormModel 创建覆盖共享连接用户名和私钥的表单
+func newOverrideFormModel(mainModel tea.Model, overrideStore database.OverrideStore, conn models.ConnInfo) formModel {
+	m := newFormModel(mainModel, nil, nil, conn)
+	m.overrideStore = overrideStore
+	m.title = "Override " + conn.Name + " (" + conn.Source + ")"
+	m.inputs[idxUsername].SetValue("")
+	m.inputs[idxPrivateKey].SetValue("")
+	overrides, err := overrideStore.GetOverrides()
+	if err != nil {
+		m.err = err
+	}
+	for _, o := range overrides {
+		if o.Source == conn.Source && o.Name == conn.Name {
+			m.inputs[idxUsername].SetValue(o.Username)
+			m.inputs[idxPrivateKey].SetValue(o.PrivateKey)
+		}
+	}
+	m.inputs[idxUsername].Placeholder = "Use shared value"
+	m.inputs[idxPrivateKey].Placeholder = "Use shared value"
+	m.inputs[idxName].Blur()
+	m.focusIndex = idxUsername
+	m.inputs[idxUsername].Focus()
 	return m
 }
 
@@ -129,136 +273,409 @@ func (m formModel) Init() tea.Cmd {
 
 func (m formModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case tea.MouseMsg:
+		if leftClick(msg) {
+			return m.click(msg.X, msg.Y)
+		}
+		return m, nil
 	case tea.KeyMsg:
-		switch msg.Type {
-		case tea.KeyEsc:
+		// 多行文本框中回车换行、上下键移动光标, 只能用 tab 切换焦点
+		if isArea(m.focusIndex) {
+			a := m.areas[m.focusIndex-idxNotes]
+			if key.Matches(msg, a.KeyMap.InsertNewline, a.KeyMap.LineNext, a.KeyMap.LinePrevious) {
+				return m, m.updateInputs(msg)
+			}
+		}
+		switch {
+		case key.Matches(msg, m.keyMap.Cancel):
 			return m.mainModel, nil
-		case tea.KeyEnter:
-			if m.focusIndex == idxEnter {
-				port, _ := strconv.Atoi(m.inputs[idxPort].Value())
-				conn := models.ConnInfo{
-					ID:         m.conn.ID,
-					Name:       m.inputs[idxName].Value(),
-					Host:       m.inputs[idxHost].Value(),
-					Port:       port,
-					Username:   m.inputs[idxUsername].Value(),
-					AuthType:   m.authTypeSelected,
-					Password:   m.inputs[idxPass].Value(),
-					PrivateKey: m.inputs[idxPrivateKey].Value(),
-				}
-				v := validator.New(validator.WithRequiredStructEnabled())
-				err := v.Struct(conn)
-				if err != nil {
-					m.err = err
-					return m, nil
-				}
-				if m.isEdit {
-					err = m.db.UpdateConnection(conn)
-				} else {
-					err = m.db.AddConnection(conn)
-				}
-				if err != nil {
-					m.err = err
-					return m, nil
-				}
-
-				connections, err := m.db.GetAllConnections()
-				if err != nil {
-					m.err = err
-					return m, nil
-				}
-				mainModel := m.mainModel.(*MainModel)
-				mainModel.connections = connections
-				mainModel.updateTable()
-
-				return m.mainModel, nil
-			} else if m.focusIndex == idxCancel {
-				return m.mainModel, nil
-			} else {
-				m.changeFoucs(1)
+		case key.Matches(msg, m.keyMap.Help):
+			m.showHelp = !m.showHelp
+			return m, nil
+		case key.Matches(msg, m.keyMap.Submit):
+			if m.focusIndex == idxEnter || m.focusIndex == idxCancel {
+				return m.submit()
 			}
+			m.changeFoucs(1)
 
-		case tea.KeyTab, tea.KeyDown:
+		case key.Matches(msg, m.keyMap.Next):
 			m.changeFoucs(1)
-		case tea.KeyShiftTab, tea.KeyUp:
+		case key.Matches(msg, m.keyMap.Prev):
 			m.changeFoucs(-1)
-		case tea.KeySpace, tea.KeyLeft, tea.KeyRight, keyCharH, keyCharL:
-			if m.focusIndex == idxAuthType {
+		case key.Matches(msg, m.keyMap.Toggle, m.keyMap.Left, m.keyMap.Right):
+			left := key.Matches(msg, m.keyMap.Left)
+			switch m.focusIndex {
+			case idxAuthType:
 				if m.authTypeSelected == models.UsePass {
 					m.authTypeSelected = models.UseKey
 				} else {
 					m.authTypeSelected = models.UsePass
 				}
+			case idxImportKey:
+				m.importKey = !m.importKey
+			case idxSecretBackend:
+				n := len(models.SecretBackends)
+				if left {
+					m.secretBackend = (m.secretBackend - 1 + n) % n
+				} else {
+					m.secretBackend = (m.secretBackend + 1) % n
+				}
+				m.updateSecretRefPlaceholder()
+			case idxIdentity:
+				n := len(m.identities) + 1
+				if left {
+					m.identitySelected = (m.identitySelected - 1 + n) % n
+				} else {
+					m.identitySelected = (m.identitySelected + 1) % n
+				}
+			case idxExtraIdentities:
+				n := len(m.identities)
+				switch {
+				case key.Matches(msg, m.keyMap.Toggle):
+					id := m.identities[m.extraCursor].ID
+					m.extraSelected[id] = !m.extraSelected[id]
+				case left:
+					m.extraCursor = (m.extraCursor - 1 + n) % n
+				default:
+					m.extraCursor = (m.extraCursor + 1) % n
+				}
 			}
 		}
 	}
 	cmd := m.updateInputs(msg)
 	return m, cmd
 }
-func (m *formModel) changeFoucs(i int) {
-	if m.focusIndex <= idxPrivateKey && m.focusIndex != idxAuthType {
-		m.inputs[m.focusIndex].Blur()
+
+// submit 按下焦点所在的按钮, 保存或取消
+func (m formModel) submit() (tea.Model, tea.Cmd) {
+	if m.focusIndex == idxCancel {
+		return m.mainModel, nil
 	}
-	if i > 0 {
-		m.focusIndex = (m.focusIndex + 1) % (len(m.inputs) + 2)
-		if m.focusIndex <= idxPrivateKey && m.focusIndex != idxAuthType {
-			if m.focusIndex == idxPass && m.authTypeSelected == models.UseKey {
-				m.focusIndex = idxPrivateKey
-			}
-			m.inputs[m.focusIndex].Focus()
-			if m.focusIndex == idxPrivateKey && m.authTypeSelected == models.UsePass {
-				m.focusIndex = m.focusIndex + 1
+	var err error
+	if m.overrideStore != nil {
+		err = m.saveOverride()
+	} else if m.forIdentity {
+		err = m.saveIdentity()
+	} else {
+		err = m.saveConn()
+	}
+	if err != nil {
+		m.err = err
+		return m, nil
+	}
+	return m.mainModel, nil
+}
+
+// updateSecretRefPlaceholder 根据选择的密码存储位置提示引用格式
+func (m *formModel) updateSecretRefPlaceholder() {
+	switch models.SecretBackends[m.secretBackend] {
+	case models.SecretBackendCommand:
+		m.inputs[idxSecretRef].Placeholder = "pass show servers/web"
+	case models.SecretBackendVault:
+		m.inputs[idxSecretRef].Placeholder = "secret/data/servers/web#password"
+	}
+}
+
+// usesSecretRef 判断密码是否保存在外部存储中
+func (m formModel) usesSecretRef() bool {
+	return !m.forIdentity && models.SecretBackends[m.secretBackend] != models.SecretBackendLocal
+}
+
+// credential 收集表单中填写的用户名和认证信息
+func (m formModel) credential() (models.Identity, error) {
+	cred := models.Identity{
+		Username:   m.inputs[idxUsername].Value(),
+		AuthType:   m.authTypeSelected,
+		Password:   m.inputs[idxPass].Value(),
+		PrivateKey: m.inputs[idxPrivateKey].Value(),
+	}
+	if cred.AuthType == models.UseKey {
+		cred.KeyPassphrase = m.inputs[idxPassphrase].Value()
+		if m.importKey {
+			data, err := m.importedKey(cred.PrivateKey, cred.KeyPassphrase)
+			if err != nil {
+				return cred, err
 			}
+			cred.KeyData = data
+		}
+	}
+	return cred, nil
+}
+
+func (m formModel) saveConn() error {
+	port, _ := strconv.Atoi(m.inputs[idxPort].Value())
+	conn := models.ConnInfo{
+		ID:         m.conn.ID,
+		Version:    m.conn.Version,
+		Name:       m.inputs[idxName].Value(),
+		Host:       m.inputs[idxHost].Value(),
+		Port:       port,
+		TOTPSecret: strings.TrimSpace(m.inputs[idxTOTP].Value()),
+		Notes:      strings.TrimRight(m.areas[idxNotes-idxNotes].Value(), " \n"),
+	}
+	meta, err := models.ParseMeta(m.areas[idxMeta-idxNotes].Value())
+	if err != nil {
+		return err
+	}
+	conn.Meta = meta
+	for _, identity := range m.identities {
+		if m.extraSelected[identity.ID] {
+			conn.ExtraIdentityIDs = append(conn.ExtraIdentityIDs, identity.ID)
 		}
+	}
+	if m.identitySelected > 0 {
+		conn.IdentityID = m.identities[m.identitySelected-1].ID
+		conn.AuthType = m.authTypeSelected
 	} else {
-		l := len(m.inputs) + 2
-		m.focusIndex = (m.focusIndex - 1 + l) % l
-		if m.focusIndex <= idxPrivateKey && m.focusIndex != idxAuthType {
-			if m.focusIndex == idxPrivateKey && m.authTypeSelected == models.UsePass {
-				m.focusIndex = idxPass
-			}
-			m.inputs[m.focusIndex].Focus()
-			if m.focusIndex == idxPass && m.authTypeSelected == models.UseKey {
-				m.focusIndex = m.focusIndex - 1
+		cred, err := m.credential()
+		if err != nil {
+			return err
+		}
+		conn.ApplyIdentity(cred)
+		if conn.AuthType == models.UsePass {
+			conn.SecretBackend = models.SecretBackends[m.secretBackend]
+			if conn.SecretBackend != models.SecretBackendLocal {
+				conn.SecretRef = strings.TrimSpace(m.inputs[idxSecretRef].Value())
 			}
 		}
 	}
+	v := validator.New(validator.WithRequiredStructEnabled())
+	if err := v.Struct(conn); err != nil {
+		return err
+	}
+	if m.isEdit {
+		err = m.db.UpdateConnection(conn)
+	} else {
+		err = m.db.AddConnection(conn)
+	}
+	if err != nil {
+		return err
+	}
+
+	return m.mainModel.(*MainModel).reloadConnections()
+}
+
+func (m formModel) saveOverride() error {
+	err := m.overrideStore.SetOverride(models.Override{
+		Source:     m.conn.Source,
+		Name:       m.conn.Name,
+		Username:   strings.TrimSpace(m.inputs[idxUsername].Value()),
+		PrivateKey: strings.TrimSpace(m.inputs[idxPrivateKey].Value()),
+	})
+	if err != nil {
+		return err
+	}
+	return m.mainModel.(*MainModel).reloadConnections()
+}
+
+func (m formModel) saveIdentity() error {
+	identity, err := m.credential()
+	if err != nil {
+		return err
+	}
+	identity.ID = m.conn.ID
+	identity.Name = m.inputs[idxName].Value()
+	v := validator.New(validator.WithRequiredStructEnabled())
+	if err := v.Struct(identity); err != nil {
+		return err
+	}
+	if m.isEdit {
+		err = m.identityStore.UpdateIdentity(identity)
+	} else {
+		err = m.identityStore.AddIdentity(identity)
+	}
+	if err != nil {
+		return err
+	}
+	m.mainModel.(*identitiesModel).reload()
+	return nil
+}
+
+// importedKey 返回需要导入的私钥内容
+// 私钥文件不可读且之前已导入过私钥时沿用已导入的内容
+func (m formModel) importedKey(path string, passphrase string) (string, error) {
+	if passphrase == "" && m.conn.KeyPassphrase != "" {
+		p, err := models.DecryptString(m.conn.KeyPassphrase)
+		if err != nil {
+			return "", err
+		}
+		passphrase = p
+	}
+	data, err := ssh.ReadPrivateKey(path, passphrase)
+	if err == nil {
+		return string(data), nil
+	}
+	if m.conn.KeyData != "" && strings.TrimSpace(path) == m.conn.PrivateKey {
+		return models.DecryptString(m.conn.KeyData)
+	}
+	if strings.TrimSpace(path) == "" {
+		return "", errors.New("private key path is required to import the key")
+	}
+	return "", err
+}
+
+// isInput 判断该位置是否为输入框
+func isInput(idx int) bool {
+	switch idx {
+	case idxAuthType, idxSecretBackend, idxImportKey, idxIdentity, idxExtraIdentities:
+		return false
+	}
+	return idx < idxEnter
+}
+
+// isArea 判断该位置是否为多行文本框
+func isArea(idx int) bool {
+	return idx >= idxNotes
+}
+
+// focusOrder 返回当前认证方式下可获得焦点的位置
+func (m formModel) focusOrder() []int {
+	if m.overrideStore != nil {
+		return []int{idxUsername, idxPrivateKey, idxEnter, idxCancel}
+	}
+	order := []int{idxName}
+	if !m.forIdentity {
+		order = append(order, idxHost, idxPort)
+		if len(m.identities) > 0 {
+			order = append(order, idxIdentity, idxExtraIdentities)
+		}
+		if m.identitySelected > 0 {
+			return append(order, idxTOTP, idxNotes, idxMeta, idxEnter, idxCancel)
+		}
+	}
+	order = append(order, idxUsername, idxAuthType)
+	if m.authTypeSelected == models.UsePass {
+		if !m.forIdentity {
+			order = append(order, idxSecretBackend)
+		}
+		if m.usesSecretRef() {
+			order = append(order, idxSecretRef)
+		} else {
+			order = append(order, idxPass)
+		}
+	} else {
+		order = append(order, idxPrivateKey, idxPassphrase, idxImportKey)
+	}
+	if !m.forIdentity {
+		order = append(order, idxTOTP, idxNotes, idxMeta)
+	}
+	return append(order, idxEnter, idxCancel)
+}
+
+func (m *formModel) changeFoucs(i int) {
+	order := m.focusOrder()
+	pos := 0
+	for p, idx := range order {
+		if idx == m.focusIndex {
+			pos = p
+			break
+		}
+	}
+	pos = (pos + i + len(order)) % len(order)
+	m.setFocus(order[pos])
+}
+
+// setFocus 将焦点移到 idx 处的元素
+func (m *formModel) setFocus(idx int) {
+	if isInput(m.focusIndex) {
+		m.inputs[m.focusIndex].Blur()
+	}
+	if isArea(m.focusIndex) {
+		m.areas[m.focusIndex-idxNotes].Blur()
+	}
+	m.focusIndex = idx
+	if isInput(m.focusIndex) {
+		m.inputs[m.focusIndex].Focus()
+	}
+	if isArea(m.focusIndex) {
+		m.areas[m.focusIndex-idxNotes].Focus()
+	}
 }
 
 func (m formModel) updateInputs(msg tea.Msg) tea.Cmd {
-	cmds := make([]tea.Cmd, len(m.inputs))
+	cmds := make([]tea.Cmd, len(m.inputs)+len(m.areas))
 
 	for i := range m.inputs {
 		m.inputs[i], cmds[i] = m.inputs[i].Update(msg)
 	}
+	for i := range m.areas {
+		m.areas[i], cmds[len(m.inputs)+i] = m.areas[i].Update(msg)
+	}
 
 	return tea.Batch(cmds...)
 }
 
 func (m formModel) View() string {
 	var b strings.Builder
+	for _, section := range m.sections() {
+		b.WriteString(section.view)
+	}
+	return b.String()
+}
 
-	b.WriteString(titleStyle.Render(m.title) + "\n\n")
-	b.WriteString(m.inputView(idxName) + "\n\n")
-	b.WriteString(m.inputView(idxHost) + "\n\n")
-	b.WriteString(m.inputView(idxPort) + "\n\n")
-	b.WriteString(m.inputView(idxUsername) + "\n\n")
-	b.WriteString(m.authTypeView())
-	b.WriteString("\n\n")
-	if m.authTypeSelected == models.UsePass {
-		b.WriteString(m.inputView(idxPass) + "\n")
+// sections 依次列出表单显示的各部分, 鼠标点击时据此找到点击的元素
+func (m formModel) sections() []formSection {
+	var s []formSection
+	add := func(idx int, view string) {
+		s = append(s, formSection{idx, view})
+	}
+	field := func(idx int, view string) {
+		add(idx, view+"\n\n")
+	}
+	add(-1, titleStyle.Render(m.title)+"\n\n")
+	if m.overrideStore != nil {
+		// 共享连接的覆盖设置, 设置私钥后改用私钥认证
+		add(-1, noStyle.Render("  Host "+m.conn.Host)+"\n\n")
+		field(idxUsername, m.inputView(idxUsername))
+		field(idxPrivateKey, m.inputView(idxPrivateKey))
 	} else {
-		b.WriteString(m.inputView(idxPrivateKey) + "\n")
+		field(idxName, m.inputView(idxName))
+		if !m.forIdentity {
+			field(idxHost, m.inputView(idxHost))
+			field(idxPort, m.inputView(idxPort))
+			if len(m.identities) > 0 {
+				field(idxIdentity, m.identityView())
+				field(idxExtraIdentities, m.extraIdentitiesView())
+			}
+		}
+		if m.identitySelected == 0 {
+			field(idxUsername, m.inputView(idxUsername))
+			field(idxAuthType, m.authTypeView())
+			if m.authTypeSelected == models.UsePass {
+				if !m.forIdentity {
+					field(idxSecretBackend, m.secretBackendView())
+				}
+				if m.usesSecretRef() {
+					field(idxSecretRef, m.inputView(idxSecretRef))
+				} else {
+					field(idxPass, m.inputView(idxPass))
+				}
+			} else {
+				field(idxPrivateKey, m.inputView(idxPrivateKey))
+				field(idxPassphrase, m.inputView(idxPassphrase))
+				field(idxImportKey, m.importKeyView())
+			}
+		}
+		if !m.forIdentity {
+			field(idxTOTP, m.inputView(idxTOTP))
+			field(idxNotes, m.areaView(idxNotes, "Notes"))
+			field(idxMeta, m.areaView(idxMeta, "Meta (key: value per line)"))
+		}
 	}
+	// 最后一项之后只空一行, 用于显示错误, 没有错误时显示焦点所在元素的说明
+	s[len(s)-1].view = strings.TrimSuffix(s[len(s)-1].view, "\n")
 	if m.err != nil {
-		b.WriteString(errorStyle.Render(m.err.Error()) + "\n")
+		add(-1, errorStyle.Render(m.err.Error())+"\n")
 	} else {
-		b.WriteString("\n")
+		add(-1, helpStyle.Render(m.fieldHint())+"\n")
 	}
-
-	b.WriteString(m.buttonView())
-	b.WriteString("\n\n")
-	return b.String()
+	add(idxEnter, m.buttonView())
+	keys := m.help.ShortHelpView(m.keyMap.ShortHelp())
+	if m.showHelp {
+		keys = fullHelpView(m.help, m.keyMap.FullHelp(), 0)
+	}
+	add(-1, "\n\n"+keys+"\n")
+	return s
 }
 
 func (m formModel) authTypeView() string {
@@ -278,11 +695,106 @@ func (m formModel) authTypeView() string {
 	return b.String()
 }
 
+// secretBackendNames 密码存储位置的显示名称
+var secretBackendNames = map[string]string{
+	models.SecretBackendLocal:   "Local",
+	models.SecretBackendCommand: "Command",
+	models.SecretBackendVault:   "Vault",
+}
+
+func (m formModel) secretBackendView() string {
+	var b strings.Builder
+	if m.focusIndex == idxSecretBackend {
+		b.WriteString(focusedStyle.Render("> Store "))
+	} else {
+		b.WriteString(noStyle.Render("  Store "))
+	}
+	for i, backend := range models.SecretBackends {
+		if i == m.secretBackend {
+			b.WriteString(focusedStyle.Render("(x)" + secretBackendNames[backend]))
+		} else {
+			b.WriteString("( )" + secretBackendNames[backend])
+		}
+		if i < len(models.SecretBackends)-1 {
+			b.WriteString("   ")
+		}
+	}
+	return b.String()
+}
+
+func (m formModel) identityView() string {
+	var b strings.Builder
+	if m.focusIndex == idxIdentity {
+		b.WriteString(focusedStyle.Render("> Identity "))
+	} else {
+		b.WriteString(noStyle.Render("  Identity "))
+	}
+	names := []string{"None"}
+	for _, identity := range m.identities {
+		names = append(names, identity.Name)
+	}
+	for i, name := range names {
+		if i == m.identitySelected {
+			b.WriteString(focusedStyle.Render("(x)" + name))
+		} else {
+			b.WriteString("( )" + name)
+		}
+		if i < len(names)-1 {
+			b.WriteString("   ")
+		}
+	}
+	return b.String()
+}
+
+// extraIdentitiesView 显示该主机可选的其他登录身份
+func (m formModel) extraIdentitiesView() string {
+	var b strings.Builder
+	focused := m.focusIndex == idxExtraIdentities
+	if focused {
+		b.WriteString(focusedStyle.Render("> Also as "))
+	} else {
+		b.WriteString(noStyle.Render("  Also as "))
+	}
+	for i, identity := range m.identities {
+		item := "[ ]" + identity.Name
+		if m.extraSelected[identity.ID] {
+			item = "[x]" + identity.Name
+		}
+		if focused && i == m.extraCursor {
+			b.WriteString(focusedStyle.Underline(true).Render(item))
+		} else if m.extraSelected[identity.ID] {
+			b.WriteString(focusedStyle.Render(item))
+		} else {
+			b.WriteString(item)
+		}
+		if i < len(m.identities)-1 {
+			b.WriteString("   ")
+		}
+	}
+	return b.String()
+}
+
+func (m formModel) importKeyView() string {
+	var b strings.Builder
+	if m.focusIndex == idxImportKey {
+		b.WriteString(focusedStyle.Render("> Import "))
+	} else {
+		b.WriteString(noStyle.Render("  Import "))
+	}
+	if m.importKey {
+		b.WriteString(focusedStyle.Render("[x]Store key in tssh"))
+	} else {
+		b.WriteString("[ ]Store key in tssh")
+	}
+	return b.String()
+}
+
 func (m formModel) inputView(idx int) string {
-	if idx > idxPrivateKey || idx == idxAuthType {
+	if !isInput(idx) {
 		return ""
 	}
 	t := m.inputs[idx]
+	t.PlaceholderStyle = placeholderStyle
 	if idx == m.focusIndex {
 		t.Prompt = strings.Replace(t.Prompt, " ", ">", 1)
 		t.PromptStyle = focusedStyle
@@ -293,17 +805,38 @@ func (m formModel) inputView(idx int) string {
 	return t.View()
 }
 
+// areaView 显示多行文本框及其标题
+func (m formModel) areaView(idx int, label string) string {
+	a := m.areas[idx-idxNotes]
+	for _, style := range []*textarea.Style{&a.FocusedStyle, &a.BlurredStyle} {
+		style.Placeholder = placeholderStyle
+		style.CursorLine = lipgloss.NewStyle()
+		style.Prompt = noStyle
+	}
+	a.FocusedStyle.Prompt = focusedStyle
+	if idx == m.focusIndex {
+		return focusedStyle.Render("> "+label) + "\n" + a.View()
+	}
+	return noStyle.Render("  "+label) + "\n" + a.View()
+}
+
+// 表单底部的按钮
+const (
+	enterButton  = "  [ Enter ]   "
+	cancelButton = "  [ Cancel ]   "
+)
+
 func (m formModel) buttonView() string {
 	var b strings.Builder
 	if m.focusIndex == idxEnter {
-		b.WriteString(focusedStyle.Render("  [ Enter ]   "))
+		b.WriteString(focusedStyle.Render(enterButton))
 	} else {
-		b.WriteString(noStyle.Render("  [ Enter ]   "))
+		b.WriteString(noStyle.Render(enterButton))
 	}
 	if m.focusIndex == idxCancel {
-		b.WriteString(focusedStyle.Render("  [ Cancel ]   "))
+		b.WriteString(focusedStyle.Render(cancelButton))
 	} else {
-		b.WriteString(noStyle.Render("  [ Cancel ]   "))
+		b.WriteString(noStyle.Render(cancelButton))
 	}
 	return b.String()
 }
@@ -0,0 +1,152 @@
+package ui
+
+import (
+	"strings"
+	"tssh/models"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// helpGroupGap 完整帮助中各组按键之间的间隔
+const helpGroupGap = "    "
+
+// newHelp 创建使用当前主题样式的帮助
+func newHelp() help.Model {
+	h := help.New()
+	h.ShortSeparator = "  "
+	h.FullSeparator = helpGroupGap
+	h.Styles = help.Styles{
+		Ellipsis:       helpStyle,
+		ShortKey:       helpKeyStyle,
+		ShortDesc:      helpStyle,
+		ShortSeparator: helpStyle,
+		FullKey:        focusedStyle,
+		FullDesc:       lipgloss.NewStyle(),
+		FullSeparator:  helpStyle,
+	}
+	return h
+}
+
+// fullHelpView 按组显示所有启用的按键, 一行放不下的组换到下一行而不是像 help.Model 那样省略
+func fullHelpView(h help.Model, groups [][]key.Binding, width int) string {
+	h.Width = 0
+	var rows [][][]key.Binding
+	var row [][]key.Binding
+	for _, group := range groups {
+		if !groupEnabled(group) {
+			continue
+		}
+		if len(row) > 0 && width > 0 && lipgloss.Width(h.FullHelpView(append(row, group))) > width {
+			rows = append(rows, row)
+			row = nil
+		}
+		row = append(row, group)
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	views := make([]string, len(rows))
+	for i, row := range rows {
+		views[i] = h.FullHelpView(row)
+	}
+	return strings.Join(views, "\n\n")
+}
+
+// groupEnabled 组中是否有启用的按键, 没有时整组不显示
+func groupEnabled(group []key.Binding) bool {
+	for _, b := range group {
+		if b.Enabled() {
+			return true
+		}
+	}
+	return false
+}
+
+// helpView 主界面的帮助窗口, 过滤时只列出过滤相关的按键
+func (m *MainModel) helpView() string {
+	title := "Keys"
+	if m.filterInput.Focused() {
+		title = "Filter keys"
+	}
+	width := m.width - dialogBoxStyle.GetHorizontalFrameSize()
+	lines := []string{
+		titleStyle.Render(title),
+		"",
+		fullHelpView(m.help, m.keyMap.FullHelp(), width),
+		"",
+	}
+	if m.filterInput.Focused() {
+		lines = append(lines, "Matches name, host, username, notes and key=value metadata.", "")
+	}
+	lines = append(lines, helpStyle.Render("Press any key to close"))
+	return dialogBoxStyle.Render(strings.Join(lines, "\n"))
+}
+
+// fieldHint 说明表单中焦点所在元素的用途和填写要求
+func (m formModel) fieldHint() string {
+	toggle := m.keyMap.Toggle.Help().Key
+	choose := m.keyMap.Left.Help().Key + "/" + m.keyMap.Right.Help().Key
+	next := m.keyMap.Next.Help().Key
+	switch m.focusIndex {
+	case idxName:
+		if m.forIdentity {
+			return "Required. Shown when choosing the identity for a connection."
+		}
+		return "Required. Shown in the list and used by the filter."
+	case idxHost:
+		return "Required. Hostname or IP address of the server."
+	case idxPort:
+		return "Required. SSH port, must not be 0 (usually 22)."
+	case idxIdentity:
+		return "Press " + choose + " to log in with a shared identity instead of the user and credentials below."
+	case idxExtraIdentities:
+		return "Other identities offered when connecting. " + choose + " moves, " + toggle + " selects."
+	case idxUsername:
+		if m.overrideStore != nil {
+			return "Login user on this machine. Empty uses the shared value."
+		}
+		return "Required. Login user on the server."
+	case idxAuthType:
+		return "Press " + choose + " or " + toggle + " to switch between password and private key."
+	case idxSecretBackend:
+		return "Local stores the password encrypted in tssh; command and vault read it when connecting."
+	case idxPass:
+		if m.isEdit {
+			return "Empty keeps the current password."
+		}
+		return "Required for password authentication. Stored encrypted."
+	case idxSecretRef:
+		if models.SecretBackends[m.secretBackend] == models.SecretBackendVault {
+			return "Required. Vault path and field, e.g. secret/data/servers/web#password."
+		}
+		return "Required. Command printing the password, e.g. pass show servers/web."
+	case idxPrivateKey:
+		if m.overrideStore != nil {
+			return "Key used on this machine, switches to key authentication. Empty uses the shared value."
+		}
+		return "Path of the private key. Empty uses connect.default_key from the config."
+	case idxPassphrase:
+		if m.isEdit {
+			return "Passphrase of an encrypted key. Empty keeps the current one."
+		}
+		return "Passphrase of an encrypted key, leave empty if the key has none."
+	case idxImportKey:
+		return "Press " + toggle + " to copy the key into tssh, so the file is no longer needed."
+	case idxTOTP:
+		if m.conn.TOTPSecret != "" {
+			return "Base32 secret or otpauth:// URI for one-time codes. Empty keeps the current one."
+		}
+		return "Optional. Base32 secret or otpauth:// URI for one-time codes."
+	case idxNotes:
+		return "Optional Markdown, shown in the details panel. Press " + next + " to leave the box."
+	case idxMeta:
+		return "One key: value (or key=value) per line, keys must be unique. Press " + next + " to leave the box."
+	case idxEnter:
+		return "Save and return to the list."
+	case idxCancel:
+		return "Discard the changes."
+	}
+	return ""
+}
@@ -0,0 +1,202 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"tssh/database"
+	"tssh/models"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// historyModel 显示连接或共享身份的修改记录或已删除的连接, 可撤销选中的修改
+type historyModel struct {
+	table     table.Model
+	entries   []models.HistoryEntry
+	mainModel tea.Model // 显示身份的修改记录时为身份列表
+	store     database.HistoryStore
+	conn      *models.ConnInfo
+	identity  *models.Identity // 不为 nil 时显示该身份的修改记录
+	deleted   bool             // 为 true 时显示已删除的连接
+	keyMap    *HistoryKeyMap
+	status    string
+	err       error
+}
+
+var historyColumns = []column{
+	{Title: "Time", Width: 16},
+	{Title: "Action", Width: 7},
+	{Title: "Name", Width: 15, Flex: 1},
+	{Title: "Changes", Width: 40, Flex: 3},
+}
+
+func newHistoryModel(mainModel tea.Model, store database.HistoryStore, conn *models.ConnInfo) *historyModel {
+	m := newHistoryTable(mainModel, store)
+	m.conn = conn
+	m.deleted = conn == nil
+	m.keyMap.Deleted.SetEnabled(conn != nil)
+	m.reload()
+	main := mainModel.(*MainModel)
+	m.resize(main.width, main.height)
+	return m
+}
+
+// newIdentityHistoryModel 显示共享身份的修改记录, 返回时回到身份列表
+func newIdentityHistoryModel(parent *identitiesModel, store database.HistoryStore, identity models.Identity) *historyModel {
+	m := newHistoryTable(parent, store)
+	m.identity = &identity
+	m.keyMap.Deleted.SetEnabled(false)
+	m.reload()
+	main := parent.mainModel.(*MainModel)
+	m.resize(main.width, main.height)
+	return m
+}
+
+func newHistoryTable(mainModel tea.Model, store database.HistoryStore) *historyModel {
+	_, columns := fitColumns(historyColumns, 0)
+	t := table.New(
+		table.WithColumns(columns),
+		table.WithFocused(true),
+		table.WithHeight(defaultTableHeight),
+	)
+	t.SetStyles(tableStyles())
+
+	return &historyModel{
+		table:     t,
+		mainModel: mainModel,
+		store:     store,
+		keyMap:    NewHistoryKeyMap(),
+	}
+}
+
+func (m *historyModel) reload() {
+	var entries []models.HistoryEntry
+	var err error
+	switch {
+	case m.identity != nil:
+		entries, err = m.store.IdentityHistory(m.identity.ID)
+	case m.deleted:
+		entries, err = m.store.DeletedConnections()
+	default:
+		entries, err = m.store.ConnectionHistory(m.conn.ID)
+	}
+	if err != nil {
+		m.err = err
+		return
+	}
+	m.entries = entries
+	rows := make([]table.Row, 0, len(entries))
+	for _, entry := range entries {
+		fields := make([]string, 0, len(entry.Changes))
+		for _, c := range entry.Changes {
+			fields = append(fields, c.Field)
+		}
+		rows = append(rows, table.Row{
+			entry.CreatedAt.Local().Format("2006-01-02 15:04"),
+			entry.Action,
+			entry.Name,
+			strings.Join(fields, ", "),
+		})
+	}
+	m.table.SetRows(rows)
+	if m.table.Cursor() >= len(rows) {
+		m.table.SetCursor(0)
+	}
+}
+
+func (m *historyModel) cursor() *models.HistoryEntry {
+	idx := m.table.Cursor()
+	if idx < 0 || idx >= len(m.entries) {
+		return nil
+	}
+	return &m.entries[idx]
+}
+
+// resize 根据窗口大小调整表格
+func (m *historyModel) resize(width, height int) {
+	// 选中记录的字段变化预留三行
+	resizeTable(&m.table, historyColumns, width, height, "History", "", "\n\n\n", helpStyle.Render(helpStr(m.keyMap))+"\n")
+}
+
+func (m *historyModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *historyModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.WindowSizeMsg); ok {
+		// 主界面也需要知道窗口大小
+		m.mainModel.Update(msg)
+		m.resize(msg.Width, msg.Height)
+		return m, nil
+	}
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+		case key.Matches(msg, m.keyMap.Back):
+			return m.mainModel, nil
+		case key.Matches(msg, m.keyMap.Deleted):
+			m.deleted = !m.deleted
+			m.status, m.err = "", nil
+			m.table.SetCursor(0)
+			m.reload()
+			return m, nil
+		case key.Matches(msg, m.keyMap.Revert):
+			entry := m.cursor()
+			if entry == nil {
+				return m, nil
+			}
+			revert := m.store.RevertHistory
+			if m.identity != nil {
+				revert = m.store.RevertIdentityHistory
+			}
+			if err := revert(entry.ID); err != nil {
+				m.err = err
+				return m, nil
+			}
+			m.err = nil
+			if m.deleted {
+				m.status = fmt.Sprintf("Restored %s", entry.Name)
+			} else {
+				m.status = fmt.Sprintf("Reverted %s of %s", entry.Action, entry.CreatedAt.Local().Format("2006-01-02 15:04"))
+			}
+			m.reload()
+			if identities, ok := m.mainModel.(*identitiesModel); ok {
+				// 身份列表同时刷新连接列表
+				identities.reload()
+				m.err = identities.err
+			} else if err := m.mainModel.(*MainModel).reloadConnections(); err != nil {
+				m.err = err
+			}
+			return m, nil
+		}
+	}
+	var cmd tea.Cmd
+	m.table, cmd = m.table.Update(msg)
+	return m, cmd
+}
+
+func (m *historyModel) View() string {
+	var b strings.Builder
+	switch {
+	case m.identity != nil:
+		b.WriteString(titleStyle.Render("History of identity "+m.identity.Name) + "\n")
+	case m.deleted:
+		b.WriteString(titleStyle.Render("Deleted connections") + "\n")
+	default:
+		b.WriteString(titleStyle.Render("History of "+m.conn.Name) + "\n")
+	}
+	b.WriteString(tableStyle.Render(m.table.View()) + "\n")
+	if entry := m.cursor(); entry != nil {
+		for _, c := range entry.Changes {
+			b.WriteString(noStyle.Render("  "+c.String()) + "\n")
+		}
+	}
+	if m.err != nil {
+		b.WriteString(errorStyle.Render(m.err.Error()) + "\n")
+	} else {
+		b.WriteString(noStyle.Render(m.status) + "\n")
+	}
+	b.WriteString(helpStyle.Render(helpStr(m.keyMap)) + "\n")
+	return b.String()
+}
@@ -0,0 +1,92 @@
+package ui
+
+import (
+	"path/filepath"
+	"testing"
+	"tssh/config"
+	"tssh/database"
+	"tssh/models"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestDeleteIdentityConfirm(t *testing.T) {
+	for _, confirm := range []bool{true, false} {
+		db, err := database.NewDB(filepath.Join(t.TempDir(), "tssh.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		if err := db.AddIdentity(models.Identity{Name: "ops", Username: "deploy", AuthType: models.UseKey, PrivateKey: "~/.ssh/id_ed25519"}); err != nil {
+			t.Fatal(err)
+		}
+		cfg := config.Default()
+		cfg.UI.ProbeHosts = false
+		cfg.UI.ConfirmDelete = confirm
+		main := InitialModel(nil, db, cfg).(*MainModel)
+		im := newIdentitiesModel(main, db)
+
+		next, _ := im.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+		if confirm {
+			dialog, ok := next.(*confirmModel)
+			if !ok {
+				t.Fatalf("delete returned %T, want a confirmation dialog", next)
+			}
+			if identities, _ := db.GetAllIdentities(); len(identities) != 1 {
+				t.Fatal("identity deleted before confirmation")
+			}
+			var cmd tea.Cmd
+			next, cmd = dialog.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+			if next != tea.Model(im) || cmd == nil {
+				t.Fatalf("confirming returned %T, %v", next, cmd)
+			}
+			next.Update(cmd())
+		}
+		if identities, err := db.GetAllIdentities(); err != nil || len(identities) != 0 {
+			t.Errorf("confirm=%v: identities after delete = %v, %v", confirm, identities, err)
+		}
+	}
+}
+
+func TestRevertIdentityFromHistory(t *testing.T) {
+	db, err := database.NewDB(filepath.Join(t.TempDir(), "tssh.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := db.AddIdentity(models.Identity{Name: "ops", Username: "deploy", AuthType: models.UseKey, PrivateKey: "~/.ssh/id_ed25519"}); err != nil {
+		t.Fatal(err)
+	}
+	identities, err := db.GetAllIdentities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated := identities[0]
+	rotated.PrivateKey = "~/.ssh/id_new"
+	if err := db.UpdateIdentity(rotated); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Default()
+	cfg.UI.ProbeHosts = false
+	main := InitialModel(nil, db, cfg).(*MainModel)
+	im := newIdentitiesModel(main, db)
+	next, _ := im.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("H")})
+	hm, ok := next.(*historyModel)
+	if !ok {
+		t.Fatalf("history key returned %T", next)
+	}
+	if len(hm.entries) != 1 || hm.entries[0].Changes[0].Field != "private_key" {
+		t.Fatalf("identity history = %+v", hm.entries)
+	}
+	hm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	if hm.err != nil {
+		t.Fatal(hm.err)
+	}
+	if identity, err := db.GetIdentity(rotated.ID); err != nil || identity.PrivateKey != "~/.ssh/id_ed25519" {
+		t.Errorf("reverted identity = %+v, %v", identity, err)
+	}
+	if back, _ := hm.Update(tea.KeyMsg{Type: tea.KeyEsc}); back != tea.Model(im) {
+		t.Errorf("esc returned %T, want the identities view", back)
+	}
+}
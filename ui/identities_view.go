@@ -0,0 +1,195 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"tssh/database"
+	"tssh/models"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// deleteIdentityMsg 确认删除身份后发送
+type deleteIdentityMsg struct {
+	identity models.Identity
+}
+
+type identitiesModel struct {
+	table      table.Model
+	identities []models.Identity
+	mainModel  tea.Model
+	store      database.IdentityStore
+	keyMap     *IdentitiesKeyMap
+	status     string
+	err        error
+}
+
+var identityColumns = []column{
+	{Title: "Name", Width: 15, Flex: 2},
+	{Title: "Username", Width: 12, Flex: 1},
+	{Title: "Auth", Width: 10},
+	{Title: "Hosts", Width: 6},
+}
+
+func newIdentitiesModel(mainModel tea.Model, store database.IdentityStore) *identitiesModel {
+	_, columns := fitColumns(identityColumns, 0)
+	t := table.New(
+		table.WithColumns(columns),
+		table.WithFocused(true),
+		table.WithHeight(defaultTableHeight),
+	)
+	t.SetStyles(tableStyles())
+
+	m := &identitiesModel{
+		table:     t,
+		mainModel: mainModel,
+		store:     store,
+		keyMap:    NewIdentitiesKeyMap(),
+	}
+	main := mainModel.(*MainModel)
+	m.keyMap.History.SetEnabled(main.history != nil)
+	m.reload()
+	m.resize(main.width, main.height)
+	return m
+}
+
+// reload 重新加载身份列表, 同时刷新主界面的连接列表
+func (m *identitiesModel) reload() {
+	identities, err := m.store.GetAllIdentities()
+	if err != nil {
+		m.err = err
+		return
+	}
+	usage, err := m.store.IdentityUsage()
+	if err != nil {
+		m.err = err
+		return
+	}
+	m.identities = identities
+	rows := make([]table.Row, 0, len(identities))
+	for _, identity := range identities {
+		auth := "password"
+		if identity.AuthType == models.UseKey {
+			auth = "key"
+		}
+		rows = append(rows, table.Row{
+			identity.Name,
+			identity.Username,
+			auth,
+			fmt.Sprintf("%d", usage[identity.ID]),
+		})
+	}
+	m.table.SetRows(rows)
+
+	if err := m.mainModel.(*MainModel).reloadConnections(); err != nil {
+		m.err = err
+	}
+}
+
+func (m *identitiesModel) cursor() *models.Identity {
+	idx := m.table.Cursor()
+	if idx < 0 || idx >= len(m.identities) {
+		return nil
+	}
+	return &m.identities[idx]
+}
+
+// resize 根据窗口大小调整表格
+func (m *identitiesModel) resize(width, height int) {
+	resizeTable(&m.table, identityColumns, width, height, "Identities", "", helpStyle.Render(helpStr(m.keyMap))+"\n")
+}
+
+func (m *identitiesModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *identitiesModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.WindowSizeMsg); ok {
+		// 主界面也需要知道窗口大小
+		m.mainModel.Update(msg)
+		m.resize(msg.Width, msg.Height)
+		return m, nil
+	}
+	if msg, ok := msg.(deleteIdentityMsg); ok {
+		m.delete(msg.identity)
+		return m, nil
+	}
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+		case key.Matches(msg, m.keyMap.Back):
+			return m.mainModel, nil
+		case key.Matches(msg, m.keyMap.Add):
+			m.err = nil
+			form := newIdentityFormModel(m, m.store, models.Identity{AuthType: models.UsePass})
+			return &form, form.Init()
+		case key.Matches(msg, m.keyMap.Edit):
+			current := m.cursor()
+			if current != nil {
+				m.err = nil
+				form := newIdentityFormModel(m, m.store, *current)
+				return &form, form.Init()
+			}
+		case key.Matches(msg, m.keyMap.Link):
+			current := m.cursor()
+			if current != nil {
+				n, err := m.store.LinkMatchingConnections(current.ID)
+				if err != nil {
+					m.err = err
+					return m, nil
+				}
+				m.err = nil
+				m.status = fmt.Sprintf("Linked %d connection(s) to %s", n, current.Name)
+				m.reload()
+			}
+			return m, nil
+		case key.Matches(msg, m.keyMap.History):
+			current := m.cursor()
+			if current != nil {
+				m.err = nil
+				return newIdentityHistoryModel(m, m.mainModel.(*MainModel).history, *current), nil
+			}
+			return m, nil
+		case key.Matches(msg, m.keyMap.Delete):
+			current := m.cursor()
+			if current == nil {
+				return m, nil
+			}
+			// 与删除连接一样按配置确认
+			if !m.mainModel.(*MainModel).cfg.UI.ConfirmDelete {
+				m.delete(*current)
+				return m, nil
+			}
+			dm := newConfirmModel(m, fmt.Sprintf("Are you sure you want to delete identity %s ?", current.Name), deleteIdentityMsg{*current})
+			return &dm, nil
+		}
+	}
+	var cmd tea.Cmd
+	m.table, cmd = m.table.Update(msg)
+	return m, cmd
+}
+
+// delete 删除身份并刷新列表
+func (m *identitiesModel) delete(identity models.Identity) {
+	if err := m.store.DeleteIdentity(identity.ID); err != nil {
+		m.err = err
+		return
+	}
+	m.err = nil
+	m.status = fmt.Sprintf("Deleted identity %s", identity.Name)
+	m.reload()
+}
+
+func (m *identitiesModel) View() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("Identities") + "\n")
+	b.WriteString(tableStyle.Render(m.table.View()) + "\n")
+	if m.err != nil {
+		b.WriteString(errorStyle.Render(m.err.Error()) + "\n")
+	} else {
+		b.WriteString(noStyle.Render(m.status) + "\n")
+	}
+	b.WriteString(helpStyle.Render(helpStr(m.keyMap)) + "\n")
+	return b.String()
+}
@@ -1,6 +1,149 @@
 package ui
 
-import "github.com/charmbracelet/bubbles/key"
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// 按键映射中的每个 key.Binding 字段都可以在配置文件中重新绑定, 例如:
+//
+//	keys:
+//	  main:
+//	    delete: [x]
+//	  form:
+//	    next: [tab, ctrl+n]
+//
+// 映射名称见 keyMaps, 按键名称为字段名的 snake_case 形式
+// 字段的 scope 标签表示该按键只在特定模式下生效, 不同 scope 的按键可以相同
+// scope 为 * 的按键在所有模式下都生效, 不能与任何按键相同
+
+// keyBindings 配置文件中自定义的按键, 由 SetKeyBindings 设置
+var keyBindings map[string]map[string][]string
+
+// keyMaps 可自定义的按键映射
+var keyMaps = map[string]func() any{
+	"main":       func() any { return NewMainKeyMap() },
+	"form":       func() any { return NewFormKeyMap() },
+	"dialog":     func() any { return NewDialogKeyMap() },
+	"chooser":    func() any { return NewChooserKeyMap() },
+	"keys":       func() any { return NewKeysKeyMap() },
+	"identities": func() any { return NewIdentitiesKeyMap() },
+	"history":    func() any { return NewHistoryKeyMap() },
+	"trash":      func() any { return NewTrashKeyMap() },
+	"columns":    func() any { return NewColumnsKeyMap() },
+	"palette":    func() any { return NewPaletteKeyMap() },
+}
+
+// SetKeyBindings 检查并应用配置文件中自定义的按键
+// 映射或按键名称不存在, 或者同一映射中的两个按键绑定了相同的键时返回错误
+func SetKeyBindings(bindings map[string]map[string][]string) error {
+	for name, actions := range bindings {
+		newKeyMap, ok := keyMaps[name]
+		if !ok {
+			return fmt.Errorf("unknown key map %q", name)
+		}
+		v := reflect.ValueOf(newKeyMap()).Elem()
+		for action, keys := range actions {
+			if _, ok := bindingField(v, action); !ok {
+				return fmt.Errorf("unknown action %s.%s", name, action)
+			}
+			if len(keys) == 0 {
+				return fmt.Errorf("no keys bound to %s.%s", name, action)
+			}
+		}
+	}
+
+	old := keyBindings
+	keyBindings = bindings
+	names := make([]string, 0, len(keyMaps))
+	for name := range keyMaps {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if err := checkConflicts(name, keyMaps[name]()); err != nil {
+			keyBindings = old
+			return err
+		}
+	}
+	return nil
+}
+
+// applyKeyBindings 将自定义的按键应用到按键映射, 帮助中显示所有绑定的键
+func applyKeyBindings(name string, keyMap any) {
+	v := reflect.ValueOf(keyMap).Elem()
+	for action, keys := range keyBindings[name] {
+		f, ok := bindingField(v, action)
+		if !ok {
+			continue
+		}
+		b := f.Addr().Interface().(*key.Binding)
+		b.SetKeys(keys...)
+		b.SetHelp(strings.Join(keys, "/"), b.Help().Desc)
+	}
+}
+
+// checkConflicts 检查同一按键映射中同一 scope 的按键是否重复
+func checkConflicts(name string, keyMap any) error {
+	type boundAction struct {
+		action, scope string
+	}
+	v := reflect.ValueOf(keyMap).Elem()
+	t := v.Type()
+	bound := make(map[string][]boundAction)
+	for i := 0; i < v.NumField(); i++ {
+		b, ok := v.Field(i).Interface().(key.Binding)
+		if !ok {
+			continue
+		}
+		action := snakeCase(t.Field(i).Name)
+		scope := t.Field(i).Tag.Get("scope")
+		for _, k := range b.Keys() {
+			for _, other := range bound[k] {
+				if other.scope == scope || other.scope == "*" || scope == "*" {
+					return fmt.Errorf("key %q is bound to both %s.%s and %s.%s", k, name, other.action, name, action)
+				}
+			}
+			bound[k] = append(bound[k], boundAction{action, scope})
+		}
+	}
+	return nil
+}
+
+// bindingField 返回按键名称对应的 key.Binding 字段
+func bindingField(v reflect.Value, action string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		if snakeCase(t.Field(i).Name) != action {
+			continue
+		}
+		if _, ok := v.Field(i).Interface().(key.Binding); ok {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// snakeCase 将 SftpConnect 转换为 sftp_connect
+func snakeCase(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
 
 type MainKeyMap struct {
 	Filter       key.Binding
@@ -9,21 +152,303 @@ type MainKeyMap struct {
 	Delete       key.Binding
 	Connect      key.Binding
 	SftpConnect  key.Binding
+	Keys         key.Binding
+	Identities   key.Binding
+	History      key.Binding
+	Trash        key.Binding
+	Undo         key.Binding
+	Details      key.Binding
+	Sort         key.Binding
+	ReverseSort  key.Binding
+	Columns      key.Binding
+	Palette      key.Binding
+	Help         key.Binding
 	Quit         key.Binding
-	FilterEnter  key.Binding
-	FilterCancel key.Binding
+	FilterEnter  key.Binding `scope:"filter"`
+	FilterCancel key.Binding `scope:"*"` // 未在过滤时也可以清除过滤条件
+	FilterHelp   key.Binding `scope:"filter"`
 }
 
 func NewMainKeyMap() *MainKeyMap {
-	return &MainKeyMap{
+	km := &MainKeyMap{
 		Filter:       key.NewBinding(key.WithKeys("/"), key.WithHelp("/", "filter")),
 		Add:          key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "add")),
 		Edit:         key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "edit")),
 		Delete:       key.NewBinding(key.WithKeys("d"), key.WithHelp("d", "delete")),
 		Connect:      key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "connect")),
 		SftpConnect:  key.NewBinding(key.WithKeys("p"), key.WithHelp("p", "connect sftp")),
+		Keys:         key.NewBinding(key.WithKeys("K"), key.WithHelp("K", "keys")),
+		Identities:   key.NewBinding(key.WithKeys("I"), key.WithHelp("I", "identities")),
+		History:      key.NewBinding(key.WithKeys("H"), key.WithHelp("H", "history")),
+		Trash:        key.NewBinding(key.WithKeys("T"), key.WithHelp("T", "trash")),
+		Undo:         key.NewBinding(key.WithKeys("u"), key.WithHelp("u", "undo delete"), key.WithDisabled()),
+		Details:      key.NewBinding(key.WithKeys("v"), key.WithHelp("v", "details")),
+		Sort:         key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "sort")),
+		ReverseSort:  key.NewBinding(key.WithKeys("S"), key.WithHelp("S", "reverse sort")),
+		Columns:      key.NewBinding(key.WithKeys("C"), key.WithHelp("C", "columns")),
+		Palette:      key.NewBinding(key.WithKeys("ctrl+p"), key.WithHelp("ctrl+p", "commands")),
+		Help:         key.NewBinding(key.WithKeys("?"), key.WithHelp("?", "help")),
 		FilterEnter:  key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "filter enter")),
 		FilterCancel: key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "filter cancel")),
 		Quit:         key.NewBinding(key.WithKeys("q"), key.WithHelp("q", "quit")),
+		// 过滤时输入的 ? 是过滤内容的一部分
+		FilterHelp: key.NewBinding(key.WithKeys("f1"), key.WithHelp("f1", "help"), key.WithDisabled()),
+	}
+	applyKeyBindings("main", km)
+	return km
+}
+
+// ShortHelp 主界面底部显示的常用按键, 过滤时只显示过滤相关的按键
+func (km *MainKeyMap) ShortHelp() []key.Binding {
+	if km.FilterEnter.Enabled() {
+		return []key.Binding{km.FilterEnter, km.FilterCancel, km.FilterHelp}
+	}
+	return []key.Binding{km.Connect, km.Filter, km.Add, km.Edit, km.Delete, km.Undo, km.Palette, km.Help, km.Quit}
+}
+
+// FullHelp 帮助中按用途分组显示的所有按键, 未启用的按键不显示
+func (km *MainKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{km.Connect, km.SftpConnect, km.Details},
+		{km.Add, km.Edit, km.Delete, km.Undo},
+		{km.Filter, km.Sort, km.ReverseSort, km.Columns},
+		{km.Keys, km.Identities, km.History, km.Trash},
+		{km.Palette, km.Help, km.Quit},
+		{km.FilterEnter, km.FilterCancel, km.FilterHelp},
+	}
+}
+
+type KeysKeyMap struct {
+	Generate key.Binding
+	Install  key.Binding
+	Delete   key.Binding
+	Back     key.Binding
+}
+
+func NewKeysKeyMap() *KeysKeyMap {
+	km := &KeysKeyMap{
+		Generate: key.NewBinding(key.WithKeys("g"), key.WithHelp("g", "generate")),
+		Install:  key.NewBinding(key.WithKeys("i"), key.WithHelp("i", "install public key")),
+		Delete:   key.NewBinding(key.WithKeys("d"), key.WithHelp("d", "delete")),
+		Back:     key.NewBinding(key.WithKeys("esc", "q"), key.WithHelp("esc", "back")),
+	}
+	applyKeyBindings("keys", km)
+	return km
+}
+
+type IdentitiesKeyMap struct {
+	Add     key.Binding
+	Edit    key.Binding
+	Link    key.Binding
+	Delete  key.Binding
+	History key.Binding
+	Back    key.Binding
+}
+
+func NewIdentitiesKeyMap() *IdentitiesKeyMap {
+	km := &IdentitiesKeyMap{
+		Add:     key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "add")),
+		Edit:    key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "edit/rotate")),
+		Link:    key.NewBinding(key.WithKeys("m"), key.WithHelp("m", "link matching hosts")),
+		Delete:  key.NewBinding(key.WithKeys("d"), key.WithHelp("d", "delete")),
+		History: key.NewBinding(key.WithKeys("H"), key.WithHelp("H", "history")),
+		Back:    key.NewBinding(key.WithKeys("esc", "q"), key.WithHelp("esc", "back")),
+	}
+	applyKeyBindings("identities", km)
+	return km
+}
+
+type HistoryKeyMap struct {
+	Revert  key.Binding
+	Deleted key.Binding
+	Back    key.Binding
+}
+
+func NewHistoryKeyMap() *HistoryKeyMap {
+	km := &HistoryKeyMap{
+		Revert:  key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "revert")),
+		Deleted: key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "deleted connections")),
+		Back:    key.NewBinding(key.WithKeys("esc", "q"), key.WithHelp("esc", "back")),
+	}
+	applyKeyBindings("history", km)
+	return km
+}
+
+type TrashKeyMap struct {
+	Restore key.Binding
+	Purge   key.Binding
+	Back    key.Binding
+}
+
+func NewTrashKeyMap() *TrashKeyMap {
+	km := &TrashKeyMap{
+		Restore: key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "restore")),
+		Purge:   key.NewBinding(key.WithKeys("d"), key.WithHelp("d", "delete forever")),
+		Back:    key.NewBinding(key.WithKeys("esc", "q"), key.WithHelp("esc", "back")),
+	}
+	applyKeyBindings("trash", km)
+	return km
+}
+
+type ColumnsKeyMap struct {
+	Up       key.Binding
+	Down     key.Binding
+	Toggle   key.Binding
+	MoveUp   key.Binding
+	MoveDown key.Binding
+	Sort     key.Binding
+	Save     key.Binding
+	Back     key.Binding
+}
+
+func NewColumnsKeyMap() *ColumnsKeyMap {
+	km := &ColumnsKeyMap{
+		Up:       key.NewBinding(key.WithKeys("up", "k"), key.WithHelp("↑", "up")),
+		Down:     key.NewBinding(key.WithKeys("down", "j"), key.WithHelp("↓", "down")),
+		Toggle:   key.NewBinding(key.WithKeys(" "), key.WithHelp("space", "show/hide")),
+		MoveUp:   key.NewBinding(key.WithKeys("shift+up", "K"), key.WithHelp("K", "move up")),
+		MoveDown: key.NewBinding(key.WithKeys("shift+down", "J"), key.WithHelp("J", "move down")),
+		Sort:     key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "sort asc/desc/off")),
+		Save:     key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "save")),
+		Back:     key.NewBinding(key.WithKeys("esc", "q"), key.WithHelp("esc", "cancel")),
+	}
+	applyKeyBindings("columns", km)
+	return km
+}
+
+// PaletteKeyMap 命令面板中输入的字符用于搜索, 因此只使用方向键和控制键
+type PaletteKeyMap struct {
+	Up   key.Binding
+	Down key.Binding
+	Run  key.Binding
+	Back key.Binding
+}
+
+func NewPaletteKeyMap() *PaletteKeyMap {
+	km := &PaletteKeyMap{
+		Up:   key.NewBinding(key.WithKeys("up", "ctrl+k"), key.WithHelp("↑", "up")),
+		Down: key.NewBinding(key.WithKeys("down", "ctrl+j"), key.WithHelp("↓", "down")),
+		Run:  key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "run")),
+		Back: key.NewBinding(key.WithKeys("esc", "ctrl+p"), key.WithHelp("esc", "close")),
+	}
+	applyKeyBindings("palette", km)
+	return km
+}
+
+type FormKeyMap struct {
+	Next   key.Binding
+	Prev   key.Binding
+	Left   key.Binding
+	Right  key.Binding
+	Toggle key.Binding
+	Submit key.Binding
+	Cancel key.Binding
+	Help   key.Binding
+}
+
+func NewFormKeyMap() *FormKeyMap {
+	km := &FormKeyMap{
+		Next:   key.NewBinding(key.WithKeys("tab", "down"), key.WithHelp("tab", "next field")),
+		Prev:   key.NewBinding(key.WithKeys("shift+tab", "up"), key.WithHelp("shift+tab", "previous field")),
+		Left:   key.NewBinding(key.WithKeys("left"), key.WithHelp("←", "previous option")),
+		Right:  key.NewBinding(key.WithKeys("right"), key.WithHelp("→", "next option")),
+		Toggle: key.NewBinding(key.WithKeys(" "), key.WithHelp("space", "toggle")),
+		Submit: key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "next/submit")),
+		Cancel: key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "cancel")),
+		// 输入框中可以输入 ?, 因此使用 F1
+		Help: key.NewBinding(key.WithKeys("f1"), key.WithHelp("f1", "more help")),
+	}
+	applyKeyBindings("form", km)
+	return km
+}
+
+func (km *FormKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{km.Next, km.Prev, km.Submit, km.Cancel, km.Help}
+}
+
+func (km *FormKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{km.Next, km.Prev},
+		{km.Left, km.Right, km.Toggle},
+		{km.Submit, km.Cancel, km.Help},
+	}
+}
+
+type DialogKeyMap struct {
+	Yes     key.Binding
+	No      key.Binding
+	Switch  key.Binding
+	Confirm key.Binding
+	Help    key.Binding
+}
+
+func NewDialogKeyMap() *DialogKeyMap {
+	km := &DialogKeyMap{
+		Yes:     key.NewBinding(key.WithKeys("y"), key.WithHelp("y", "yes")),
+		No:      key.NewBinding(key.WithKeys("n", "esc", "q"), key.WithHelp("n", "no")),
+		Switch:  key.NewBinding(key.WithKeys("tab", "shift+tab", "h", "l"), key.WithHelp("tab", "switch")),
+		Confirm: key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "choose")),
+		Help:    key.NewBinding(key.WithKeys("?"), key.WithHelp("?", "more help")),
+	}
+	applyKeyBindings("dialog", km)
+	return km
+}
+
+func (km *DialogKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{km.Yes, km.No, km.Help}
+}
+
+func (km *DialogKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{km.Yes, km.No},
+		{km.Switch, km.Confirm, km.Help},
+	}
+}
+
+type ChooserKeyMap struct {
+	Up     key.Binding
+	Down   key.Binding
+	Select key.Binding
+	Back   key.Binding
+}
+
+func NewChooserKeyMap() *ChooserKeyMap {
+	km := &ChooserKeyMap{
+		Up:     key.NewBinding(key.WithKeys("up", "k", "shift+tab"), key.WithHelp("↑", "up")),
+		Down:   key.NewBinding(key.WithKeys("down", "j", "tab"), key.WithHelp("↓", "down")),
+		Select: key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "connect")),
+		Back:   key.NewBinding(key.WithKeys("esc", "q"), key.WithHelp("esc", "back")),
+	}
+	applyKeyBindings("chooser", km)
+	return km
+}
+
+// keyTypes 按键名称对应的按键类型, 如 "enter"、"ctrl+s"、"shift+up"
+var keyTypes = func() map[string]tea.KeyType {
+	types := make(map[string]tea.KeyType)
+	// 控制字符为 0 到 127, 其他特殊按键为负数
+	for t := tea.KeyType(-100); t <= 127; t++ {
+		if name := t.String(); name != "" && t != tea.KeyRunes {
+			types[name] = t
+		}
+	}
+	return types
+}()
+
+// keyPress 返回按下 k 键的消息, k 为按键映射中的按键名称, 无法识别时返回 false
+func keyPress(k string) (tea.KeyMsg, bool) {
+	var msg tea.KeyMsg
+	name := k
+	if rest, ok := strings.CutPrefix(name, "alt+"); ok && rest != "" {
+		msg.Alt, name = true, rest
+	}
+	if t, ok := keyTypes[name]; ok {
+		msg.Type = t
+	} else if runes := []rune(name); len(runes) == 1 {
+		msg.Type, msg.Runes = tea.KeyRunes, runes
+		if name == " " {
+			msg.Type = tea.KeySpace
+		}
 	}
+	return msg, msg.String() == k
 }
@@ -0,0 +1,89 @@
+package ui
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// setKeyBindings 应用自定义按键, 测试结束后恢复默认按键
+func setKeyBindings(t *testing.T, bindings map[string]map[string][]string) error {
+	t.Helper()
+	t.Cleanup(func() { keyBindings = nil })
+	return SetKeyBindings(bindings)
+}
+
+func TestDefaultKeyMapsHaveNoConflicts(t *testing.T) {
+	for name, newKeyMap := range keyMaps {
+		if err := checkConflicts(name, newKeyMap()); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestSetKeyBindingsApplies(t *testing.T) {
+	// 交换两个按键不算冲突
+	err := setKeyBindings(t, map[string]map[string][]string{
+		"main": {"delete": {"a", "x"}, "add": {"d"}, "sftp_connect": {"P"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	km := NewMainKeyMap()
+	if got := km.Delete.Keys(); !slices.Equal(got, []string{"a", "x"}) {
+		t.Errorf("delete keys = %v", got)
+	}
+	if got := km.Delete.Help().Key; got != "a/x" {
+		t.Errorf("delete help = %q, want a/x", got)
+	}
+	if got := km.SftpConnect.Keys(); !slices.Equal(got, []string{"P"}) {
+		t.Errorf("sftp_connect keys = %v", got)
+	}
+	if got := km.Edit.Keys(); !slices.Equal(got, []string{"e"}) {
+		t.Errorf("unchanged edit keys = %v", got)
+	}
+}
+
+func TestSetKeyBindingsRejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		bindings map[string]map[string][]string
+		want     string
+	}{
+		{"unknown map", map[string]map[string][]string{"nope": {"quit": {"x"}}}, `unknown key map "nope"`},
+		{"unknown action", map[string]map[string][]string{"main": {"fly": {"x"}}}, "unknown action main.fly"},
+		{"no keys", map[string]map[string][]string{"main": {"quit": {}}}, "no keys bound to main.quit"},
+		{"conflict with default", map[string]map[string][]string{"main": {"delete": {"e"}}}, `key "e" is bound to both main.edit and main.delete`},
+		{"conflict between custom", map[string]map[string][]string{"form": {"next": {"ctrl+x"}, "prev": {"ctrl+x"}}}, `key "ctrl+x" is bound to both`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := setKeyBindings(t, tt.bindings)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("SetKeyBindings error = %v, want %q", err, tt.want)
+			}
+			// 出错时保持原来的按键
+			if got := NewMainKeyMap().Delete.Keys(); !slices.Equal(got, []string{"d"}) {
+				t.Errorf("delete keys after error = %v", got)
+			}
+		})
+	}
+}
+
+func TestSetKeyBindingsScopes(t *testing.T) {
+	// 过滤时的按键与普通按键属于不同的 scope, 可以相同
+	if err := setKeyBindings(t, map[string]map[string][]string{"main": {"filter_help": {"f2"}, "help": {"f2"}}}); err != nil {
+		t.Fatalf("binding a filter key to a main key failed: %v", err)
+	}
+	err := setKeyBindings(t, map[string]map[string][]string{"main": {"filter_help": {"enter"}}})
+	if err == nil || !strings.Contains(err.Error(), "main.filter_enter") {
+		t.Errorf("conflict within the filter scope error = %v", err)
+	}
+	// filter_cancel 在所有模式下生效, 与任何 scope 的按键相同都是冲突
+	for _, action := range []string{"filter_enter", "filter_help", "quit"} {
+		err := setKeyBindings(t, map[string]map[string][]string{"main": {action: {"esc"}}})
+		if err == nil || !strings.Contains(err.Error(), "main.filter_cancel") {
+			t.Errorf("binding %s to esc error = %v, want a conflict with filter_cancel", action, err)
+		}
+	}
+}
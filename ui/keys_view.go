@@ -0,0 +1,376 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"tssh/config"
+	"tssh/database"
+	"tssh/models"
+	"tssh/ssh"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// keyInstalledMsg 公钥安装完成后返回, conn 为切换为密钥认证后的连接
+type keyInstalledMsg struct {
+	key  models.SSHKey
+	conn models.ConnInfo
+	err  error
+}
+
+// keyGeneratedMsg 密钥生成完成后返回
+type keyGeneratedMsg struct {
+	key models.SSHKey
+	err error
+}
+
+type keysModel struct {
+	table      table.Model
+	keys       []models.SSHKey
+	mainModel  tea.Model
+	store      database.KeyStore
+	target     *models.ConnInfo
+	keyMap     *KeysKeyMap
+	generating bool
+	nameInput  textinput.Model
+	keyType    int
+	unlocking  *models.SSHKey // 正在输入私钥密码的密钥
+	passInput  textinput.Model
+	busy       bool
+	status     string
+	connect    config.Connect // 安装公钥时登录服务器的设置
+	err        error
+}
+
+var keyColumns = []column{
+	{Title: "Name", Width: 20, Flex: 1},
+	{Title: "Type", Width: 8},
+	{Title: "Fingerprint", Width: 50, Flex: 2},
+	{Title: "Source", Width: 8},
+}
+
+func newKeysModel(mainModel *MainModel, store database.KeyStore, target *models.ConnInfo) keysModel {
+	_, columns := fitColumns(keyColumns, 0)
+	t := table.New(
+		table.WithColumns(columns),
+		table.WithFocused(true),
+		table.WithHeight(defaultTableHeight),
+	)
+	t.SetStyles(tableStyles())
+
+	ti := textinput.New()
+	ti.Placeholder = "Key name"
+	ti.PlaceholderStyle = placeholderStyle
+	ti.Prompt = "  Name "
+	ti.Width = 30
+	ti.CharLimit = 50
+
+	pi := textinput.New()
+	pi.Placeholder = "Key passphrase"
+	pi.PlaceholderStyle = placeholderStyle
+	pi.Prompt = "  Passphrase "
+	pi.EchoMode = textinput.EchoPassword
+	pi.Width = 30
+	pi.CharLimit = 100
+
+	m := keysModel{
+		table:     t,
+		mainModel: mainModel,
+		store:     store,
+		keyMap:    NewKeysKeyMap(),
+		nameInput: ti,
+		passInput: pi,
+		connect:   mainModel.cfg.Connect,
+	}
+	if target != nil {
+		m.target, m.err = installTarget(mainModel, *target)
+	}
+	m.reload()
+	m.resize(mainModel.width, mainModel.height)
+	return m
+}
+
+// installTarget 返回安装公钥时登录服务器使用的连接
+// 引用身份的连接使用身份的凭据; 连接本身不使用密码认证时, 改用其他身份中第一个使用密码认证的身份
+func installTarget(mainModel *MainModel, conn models.ConnInfo) (*models.ConnInfo, error) {
+	if conn.Source != "" {
+		// 共享连接的认证方式不能修改
+		return nil, database.ErrReadOnly
+	}
+	resolved := conn
+	if err := database.ResolveConnection(mainModel.db, &resolved); err != nil {
+		return &resolved, err
+	}
+	if resolved.AuthType == models.UsePass || mainModel.identities == nil {
+		return &resolved, nil
+	}
+	for _, id := range conn.ExtraIdentityIDs {
+		identity, err := mainModel.identities.GetIdentity(id)
+		if err != nil {
+			return &resolved, fmt.Errorf("failed to load identity: %w", err)
+		}
+		if identity.AuthType == models.UsePass {
+			alt := conn
+			alt.IdentityID = id
+			alt.ApplyIdentity(identity)
+			return &alt, nil
+		}
+	}
+	return &resolved, nil
+}
+
+// reload 重新加载 tssh 保存的密钥和 ~/.ssh 中的密钥
+func (m *keysModel) reload() {
+	keys, err := m.store.GetAllKeys()
+	if err != nil {
+		m.err = err
+	}
+	m.keys = append(keys, ssh.LocalKeys()...)
+	rows := make([]table.Row, 0, len(m.keys))
+	for _, k := range m.keys {
+		source := "tssh"
+		if k.Path != "" {
+			source = "~/.ssh"
+		}
+		rows = append(rows, table.Row{k.Name, k.KeyType, k.Fingerprint, source})
+	}
+	m.table.SetRows(rows)
+}
+
+func (m *keysModel) cursor() *models.SSHKey {
+	idx := m.table.Cursor()
+	if idx < 0 || idx >= len(m.keys) {
+		return nil
+	}
+	return &m.keys[idx]
+}
+
+// resize 根据窗口大小调整表格
+func (m *keysModel) resize(width, height int) {
+	resizeTable(&m.table, keyColumns, width, height, "SSH Keys", "Target", "", helpStyle.Render(helpStr(m.keyMap))+"\n")
+}
+
+func (m keysModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m keysModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.WindowSizeMsg); ok {
+		// 主界面也需要知道窗口大小
+		m.mainModel.Update(msg)
+		m.resize(msg.Width, msg.Height)
+		return m, nil
+	}
+	switch msg := msg.(type) {
+	case keyGeneratedMsg:
+		m.busy = false
+		if msg.err == nil {
+			msg.err = m.store.AddKey(msg.key)
+		}
+		if msg.err != nil {
+			m.err = msg.err
+			return m, nil
+		}
+		m.err = nil
+		m.status = fmt.Sprintf("Generated %s key %s", msg.key.KeyType, msg.key.Name)
+		m.reload()
+		return m, nil
+	case keyInstalledMsg:
+		m.busy = false
+		var missing *gossh.PassphraseMissingError
+		if errors.As(msg.err, &missing) {
+			// 私钥受密码保护, 输入密码后重新安装
+			m.unlocking = &msg.key
+			m.err = nil
+			m.status = ""
+			m.passInput.SetValue("")
+			m.passInput.Focus()
+			return m, textinput.Blink
+		}
+		if msg.err == nil {
+			msg.err = m.mainModel.(*MainModel).db.UpdateConnection(msg.conn)
+		}
+		if msg.err != nil {
+			m.err = msg.err
+			return m, nil
+		}
+		m.err = nil
+		m.status = fmt.Sprintf("Key installed, %s now uses key authentication", msg.conn.Name)
+		if err := m.mainModel.(*MainModel).reloadConnections(); err != nil {
+			m.err = err
+			return m, nil
+		}
+		m.target = nil
+		return m, nil
+	case tea.KeyMsg:
+		if m.generating {
+			return m.updateGenerate(msg)
+		}
+		if m.unlocking != nil {
+			return m.updateUnlock(msg)
+		}
+		if m.busy {
+			return m, nil
+		}
+		switch {
+		case key.Matches(msg, m.keyMap.Back):
+			return m.mainModel, nil
+		case key.Matches(msg, m.keyMap.Generate):
+			m.generating = true
+			m.nameInput.SetValue("")
+			m.nameInput.Focus()
+			return m, textinput.Blink
+		case key.Matches(msg, m.keyMap.Install):
+			k := m.cursor()
+			if k == nil {
+				return m, nil
+			}
+			if m.target == nil || m.target.AuthType != models.UsePass {
+				m.err = fmt.Errorf("select a connection using password authentication first")
+				return m, nil
+			}
+			m.busy = true
+			m.err = nil
+			m.status = fmt.Sprintf("Installing %s on %s...", k.Name, m.target.Name)
+			return m, installKeyCmd(*m.target, *k, nil, m.connect)
+		case key.Matches(msg, m.keyMap.Delete):
+			k := m.cursor()
+			if k == nil {
+				return m, nil
+			}
+			if k.Path != "" {
+				m.err = fmt.Errorf("%s is not managed by tssh", k.Path)
+				return m, nil
+			}
+			if err := m.store.DeleteKey(k.ID); err != nil {
+				m.err = err
+				return m, nil
+			}
+			m.reload()
+			return m, nil
+		}
+	}
+	var cmd tea.Cmd
+	m.table, cmd = m.table.Update(msg)
+	return m, cmd
+}
+
+func (m keysModel) updateGenerate(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.generating = false
+		m.nameInput.Blur()
+		return m, nil
+	case tea.KeyTab, tea.KeyShiftTab:
+		m.keyType = (m.keyType + 1) % len(models.KeyTypes)
+		return m, nil
+	case tea.KeyEnter:
+		name := strings.TrimSpace(m.nameInput.Value())
+		if name == "" {
+			m.err = fmt.Errorf("key name is required")
+			return m, nil
+		}
+		m.generating = false
+		m.nameInput.Blur()
+		m.busy = true
+		m.err = nil
+		m.status = "Generating key..."
+		keyType := models.KeyTypes[m.keyType]
+		return m, func() tea.Msg {
+			k, err := ssh.GenerateKey(name, keyType)
+			return keyGeneratedMsg{key: k, err: err}
+		}
+	}
+	var cmd tea.Cmd
+	m.nameInput, cmd = m.nameInput.Update(msg)
+	return m, cmd
+}
+
+// updateUnlock 输入私钥密码后重新安装公钥
+func (m keysModel) updateUnlock(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.unlocking = nil
+		m.passInput.Blur()
+		m.passInput.SetValue("")
+		return m, nil
+	case tea.KeyEnter:
+		passphrase := m.passInput.Value()
+		if passphrase == "" {
+			m.err = fmt.Errorf("passphrase is required")
+			return m, nil
+		}
+		k := *m.unlocking
+		m.unlocking = nil
+		m.passInput.Blur()
+		m.passInput.SetValue("")
+		m.busy = true
+		m.err = nil
+		m.status = fmt.Sprintf("Installing %s on %s...", k.Name, m.target.Name)
+		return m, installKeyCmd(*m.target, k, []byte(passphrase), m.connect)
+	}
+	var cmd tea.Cmd
+	m.passInput, cmd = m.passInput.Update(msg)
+	return m, cmd
+}
+
+func installKeyCmd(conn models.ConnInfo, k models.SSHKey, passphrase []byte, opts config.Connect) tea.Cmd {
+	return func() tea.Msg {
+		defer models.Zero(passphrase)
+		if err := ssh.InstallPublicKey(&conn, k, passphrase, opts); err != nil {
+			return keyInstalledMsg{key: k, err: err}
+		}
+		updated, err := ssh.SwitchToKey(conn, k, passphrase)
+		return keyInstalledMsg{key: k, conn: updated, err: err}
+	}
+}
+
+func (m keysModel) View() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("SSH Keys") + "\n")
+	if m.target != nil {
+		b.WriteString(noStyle.Render(fmt.Sprintf("Target: %s (%s@%s)", m.target.Name, m.target.Username, m.target.Host)))
+	}
+	b.WriteString("\n")
+	b.WriteString(tableStyle.Render(m.table.View()) + "\n")
+	if m.generating {
+		b.WriteString(m.nameInput.View() + "\n")
+		b.WriteString(focusedStyle.Render("  Type ") + m.keyTypeView() + "\n")
+		b.WriteString(helpStyle.Render("'tab'-switch type  'enter'-generate  'esc'-cancel") + "\n")
+		return b.String()
+	}
+	if m.unlocking != nil {
+		b.WriteString(noStyle.Render(fmt.Sprintf("%s is protected by a passphrase", m.unlocking.Name)) + "\n")
+		b.WriteString(m.passInput.View() + "\n")
+		if m.err != nil {
+			b.WriteString(errorStyle.Render(m.err.Error()) + "\n")
+		}
+		b.WriteString(helpStyle.Render("'enter'-install  'esc'-cancel") + "\n")
+		return b.String()
+	}
+	if m.err != nil {
+		b.WriteString(errorStyle.Render(m.err.Error()) + "\n")
+	} else {
+		b.WriteString(noStyle.Render(m.status) + "\n")
+	}
+	b.WriteString(helpStyle.Render(helpStr(m.keyMap)) + "\n")
+	return b.String()
+}
+
+func (m keysModel) keyTypeView() string {
+	var b strings.Builder
+	for i, t := range models.KeyTypes {
+		if i == m.keyType {
+			b.WriteString(focusedStyle.Render("(x)" + t))
+		} else {
+			b.WriteString("( )" + t)
+		}
+		b.WriteString("   ")
+	}
+	return b.String()
+}
@@ -0,0 +1,136 @@
+package ui
+
+import (
+	"slices"
+
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+)
+
+const (
+	// cellPadding 表格每个单元格左右内边距的总宽度
+	cellPadding = 2
+	// tableBorder 表格边框占用的宽度和高度
+	tableBorder = 2
+	// minColumnWidth 可伸缩列缩小后的最小宽度
+	minColumnWidth = 4
+)
+
+// column 表格列的布局
+type column struct {
+	Title string
+	// Width 首选宽度, 终端较宽时按 Flex 的比例分配剩余宽度, Flex 为 0 的列宽度固定
+	Width int
+	Flex  int
+	// Hide 终端宽度不足时按 Hide 从大到小依次隐藏, 为 0 的列总是显示
+	Hide int
+}
+
+// fitColumns 根据可用宽度计算各列的宽度, 返回显示的列在 cols 中的下标和对应的表格列
+// width 不大于 0 时 (尚未收到窗口大小) 使用首选宽度显示所有列
+func fitColumns(cols []column, width int) ([]int, []table.Column) {
+	visible := make([]int, len(cols))
+	for i := range cols {
+		visible[i] = i
+	}
+	widths := make([]int, len(cols))
+	for i, c := range cols {
+		widths[i] = c.Width
+	}
+	if width > 0 {
+		need := func() int {
+			n := 0
+			for _, i := range visible {
+				n += cols[i].Width + cellPadding
+			}
+			return n
+		}
+		// 隐藏可选的列直到首选宽度能够放下
+		for need() > width {
+			hide := -1
+			for pos, i := range visible {
+				if cols[i].Hide > 0 && (hide < 0 || cols[i].Hide > cols[visible[hide]].Hide) {
+					hide = pos
+				}
+			}
+			if hide < 0 {
+				break
+			}
+			visible = slices.Delete(visible, hide, hide+1)
+		}
+		distribute(cols, visible, widths, width-need())
+	}
+
+	columns := make([]table.Column, 0, len(visible))
+	for _, i := range visible {
+		columns = append(columns, table.Column{Title: cols[i].Title, Width: widths[i]})
+	}
+	return visible, columns
+}
+
+// distribute 将多出的宽度按比例分给可伸缩的列, extra 为负数时按比例缩小这些列
+func distribute(cols []column, visible []int, widths []int, extra int) {
+	total := 0
+	for _, i := range visible {
+		total += cols[i].Flex
+	}
+	if total == 0 || extra == 0 {
+		return
+	}
+	remain := extra
+	last := -1
+	for _, i := range visible {
+		if cols[i].Flex == 0 {
+			continue
+		}
+		d := extra * cols[i].Flex / total
+		widths[i] = max(cols[i].Width+d, minColumnWidth)
+		remain -= d
+		last = i
+	}
+	// 整除剩下的宽度给最后一个可伸缩的列
+	widths[last] = max(widths[last]+remain, minColumnWidth)
+}
+
+// projectRow 只保留显示的列
+func projectRow(row table.Row, visible []int) table.Row {
+	if len(visible) == len(row) {
+		return row
+	}
+	projected := make(table.Row, len(visible))
+	for j, i := range visible {
+		projected[j] = row[i]
+	}
+	return projected
+}
+
+// tableHeight 计算表格可以显示的高度, other 为界面中表格以外的内容
+func tableHeight(windowHeight int, other ...string) int {
+	h := windowHeight - tableBorder
+	for _, s := range other {
+		h -= lipgloss.Height(s)
+	}
+	// 至少显示表头和一行
+	return max(h, 3)
+}
+
+// truncate 超出宽度的文本以省略号结尾, width 不大于 0 时不截断
+func truncate(s string, width int) string {
+	if width <= 0 {
+		return s
+	}
+	return runewidth.Truncate(s, width, "…")
+}
+
+// resizeTable 根据窗口大小调整子界面表格的列宽和高度, 子界面的表格不隐藏列
+// width 或 height 不大于 0 时保持原样
+func resizeTable(t *table.Model, cols []column, width, height int, other ...string) {
+	if width > 0 {
+		_, columns := fitColumns(cols, width-tableBorder)
+		t.SetColumns(columns)
+	}
+	if height > 0 {
+		t.SetHeight(tableHeight(height, other...))
+	}
+}
@@ -3,10 +3,14 @@ package ui
 import (
 	"fmt"
 	"reflect"
+	"slices"
 	"strings"
+	"time"
+	"tssh/config"
 	"tssh/database"
 	"tssh/models"
 
+	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/table"
 	"github.com/charmbracelet/bubbles/textinput"
@@ -14,9 +18,6 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
-var filterFocusStyle = footStyle.Foreground(lipgloss.Color("229"))
-var filterBlurStyle = footStyle.Foreground(lipgloss.Color("240"))
-
 type FocusView int8
 
 const (
@@ -25,62 +26,50 @@ const (
 	Confirm     FocusView = 2
 )
 
-func genRow(conn *models.ConnInfo) table.Row {
-	return table.Row{
-		fmt.Sprintf("%d", conn.ID),
-		conn.Name,
-		conn.Host,
-		fmt.Sprintf("%d", conn.Port),
-		conn.Username,
-	}
-}
-
 type MainModel struct {
 	table        table.Model
+	offset       int // 表格中第一行在 currentItems 中的下标, 表格只包含显示的行
 	connections  []models.ConnInfo
 	currentItems []*models.ConnInfo
 	WillConn     *models.RunContext
 	filter       string
 	filterInput  textinput.Model
-	db           *database.DB
+	db           database.Store
+	identities   database.IdentityStore // 存储不支持共享身份时为 nil
+	keys         database.KeyStore      // 存储不支持保存密钥时为 nil
+	overrides    database.OverrideStore // 存储不支持覆盖共享连接时为 nil
+	history      database.HistoryStore  // 存储不支持修改记录时为 nil
+	trash        database.TrashStore    // 存储不支持回收站时为 nil
+	watcher      database.Watchable     // 存储无法感知其他实例的修改时为 nil
+	dataVersion  int64
 	keyMap       *MainKeyMap
+	cfg          *config.Config
+	status       string
+	undoID       int64 // 刚刚移入回收站的连接, 在下一次按键前可以撤销
+	columns      []connColumn
+	visible      []int    // 当前宽度下显示的列在 columns 中的下标
+	sort         []string // 排序列, 格式同配置中的 ui.sort
+	sortChanged  bool     // 排序已修改但尚未写入配置文件
+	width        int
+	height       int
+	showDetails  bool
+	showHelp     bool // 显示按键帮助窗口, 按任意键关闭
+	help         help.Model
+	lastClick    click
+	probes       map[string]*hostProbe  // 详情面板的主机探测结果
+	details      map[string]*connDetail // 详情面板的连接信息
 }
 
-func InitialModel(connections []models.ConnInfo, db *database.DB) tea.Model {
-	columns := []table.Column{
-		{Title: "ID", Width: 4},
-		{Title: "Name", Width: 15},
-		{Title: "Host", Width: 15},
-		{Title: "Port", Width: 6},
-		{Title: "Username", Width: 10},
-	}
-
-	rows := make([]table.Row, 0)
-	currentItems := make([]*models.ConnInfo, 0)
-	for _, conn := range connections {
-		rows = append(rows, genRow(&conn))
-		currentItems = append(currentItems, &conn)
-	}
+// defaultTableHeight 收到窗口大小之前表格的高度
+const defaultTableHeight = 10
 
+func InitialModel(connections []models.ConnInfo, db database.Store, cfg *config.Config) tea.Model {
 	t := table.New(
-		table.WithColumns(columns),
-		table.WithRows(rows),
 		table.WithFocused(true),
-		table.WithHeight(10),
+		table.WithHeight(defaultTableHeight),
 	)
 
-	s := table.DefaultStyles()
-	s.Header = s.Header.
-		BorderStyle(lipgloss.NormalBorder()).
-		BorderForeground(lipgloss.Color("240")).
-		BorderBottom(true).
-		Bold(false)
-
-	s.Selected = s.Selected.
-		Foreground(lipgloss.Color("229")).
-		Background(lipgloss.Color("57")).
-		Bold(false)
-	t.SetStyles(s)
+	t.SetStyles(tableStyles())
 
 	ti := textinput.New()
 	ti.Placeholder = ""
@@ -88,19 +77,105 @@ func InitialModel(connections []models.ConnInfo, db *database.DB) tea.Model {
 	ti.Width = 20
 	ti.CharLimit = 20
 
-	return &MainModel{
-		table:        t,
-		connections:  connections,
-		currentItems: currentItems,
-		filter:       "",
-		db:           db,
-		keyMap:       NewMainKeyMap(),
-		filterInput:  ti,
+	m := &MainModel{
+		table:       t,
+		connections: connections,
+		sort:        cfg.UI.Sort,
+		filter:      "",
+		db:          db,
+		keyMap:      NewMainKeyMap(),
+		cfg:         cfg,
+		filterInput: ti,
+		showDetails: cfg.UI.ShowDetails,
+		help:        newHelp(),
+		probes:      make(map[string]*hostProbe),
+		details:     make(map[string]*connDetail),
+	}
+	m.identities, _ = database.As[database.IdentityStore](db)
+	m.keys, _ = database.As[database.KeyStore](db)
+	m.overrides, _ = database.As[database.OverrideStore](db)
+	m.history, _ = database.As[database.HistoryStore](db)
+	m.trash, _ = database.As[database.TrashStore](db)
+	if m.watcher, _ = database.As[database.Watchable](db); m.watcher != nil {
+		m.dataVersion, _ = m.watcher.DataVersion()
+	}
+	m.setColumns(cfg.UI.Columns)
+	m.updateTable()
+	return m
+}
+
+// storeTickMsg 定期检查存储是否被其他 tssh 实例修改
+type storeTickMsg struct{}
+
+// Watch 定期通知主界面检查存储的修改, 返回停止函数
+// 子界面会忽略该消息, 返回主界面后的下一次通知时再刷新,
+// 因此由独立的 goroutine 发送而不使用 tea.Tick 串联
+func Watch(p *tea.Program, interval time.Duration) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				p.Send(storeTickMsg{})
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// checkStore 存储被其他实例修改后重新加载连接, 并保持选中的连接不变
+func (m *MainModel) checkStore() error {
+	if m.watcher == nil {
+		return nil
+	}
+	version, err := m.watcher.DataVersion()
+	if err != nil || version == m.dataVersion {
+		return err
+	}
+	m.dataVersion = version
+	var selected models.ConnInfo
+	if current := m.Cursor(); current != nil {
+		selected = *current
+	}
+	if err := m.reloadConnections(); err != nil {
+		return err
+	}
+	m.selectConn(selected)
+	return nil
+}
+
+// selectConn 将光标移到与 selected 相同的连接上
+func (m *MainModel) selectConn(selected models.ConnInfo) {
+	for i, conn := range m.currentItems {
+		// 共享连接的 ID 在重新加载后可能变化, 按名称匹配
+		same := conn.ID == selected.ID
+		if selected.Source != "" {
+			same = conn.Source == selected.Source && conn.Name == selected.Name
+		}
+		if same {
+			m.setCursor(i)
+			break
+		}
+	}
+}
+
+// reloadConnections 从存储中重新加载连接并刷新表格
+func (m *MainModel) reloadConnections() error {
+	connections, err := m.db.GetAllConnections()
+	if err != nil {
+		return err
 	}
+	m.connections = connections
+	m.updateTable()
+	return nil
 }
 func (m *MainModel) Cursor() *models.ConnInfo {
-	idx := m.table.Cursor()
-	if idx >= len(m.currentItems) {
+	idx := m.cursorRow()
+	if idx < 0 || idx >= len(m.currentItems) {
 		return nil
 	}
 	return m.currentItems[idx]
@@ -108,35 +183,106 @@ func (m *MainModel) Cursor() *models.ConnInfo {
 
 func (m *MainModel) Init() tea.Cmd {
 	m.SwitchFocus(Table)
-	return nil
+	return tea.Batch(m.detailCmd(), m.statusCmd())
 }
 
+// Update 处理消息后, 如果仍停留在主界面则为新选中的连接读取详情, 并探测状态列中的主机
 func (m *MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	model, cmd := m.update(msg)
+	if model == m {
+		cmd = tea.Batch(cmd, m.detailCmd(), m.statusCmd())
+	}
+	return model, cmd
+}
+
+func (m *MainModel) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		m.status = ""
+		if m.showHelp {
+			m.showHelp = false
+			return m, nil
+		}
+		if m.undoID != 0 && key.Matches(msg, m.keyMap.Undo) {
+			m.undoDelete()
+			return m, nil
+		}
+		m.setUndo(0)
 		switch {
 		case key.Matches(msg, m.keyMap.Connect):
-			m.WillConn = &models.RunContext{Context: m.Cursor(), Command: models.RunCommandSsh}
-			return m, tea.Quit
+			return m.connect(models.RunCommandSsh)
 		case key.Matches(msg, m.keyMap.SftpConnect):
-			m.WillConn = &models.RunContext{Context: m.Cursor(), Command: models.RunCommandSftp}
-			return m, tea.Quit
+			return m.connect(models.RunCommandSftp)
 		case key.Matches(msg, m.keyMap.Add):
 			// 创建新的添加表单
-			newForm := newFormModel(m, m.db, models.ConnInfo{Port: 22, AuthType: models.UsePass})
+			newForm := newFormModel(m, m.db, m.identities, m.newConn())
 			return &newForm, nil
 		case key.Matches(msg, m.keyMap.Edit):
 			current := m.Cursor()
+			if current != nil && current.Source != "" {
+				// 共享连接只能覆盖用户名和私钥
+				if m.overrides == nil {
+					m.status = database.ErrReadOnly.Error()
+					return m, nil
+				}
+				newForm := newOverrideFormModel(m, m.overrides, *current)
+				return &newForm, newForm.Init()
+			}
 			if current != nil {
-				newForm := newFormModel(m, m.db, *current)
+				newForm := newFormModel(m, m.db, m.identities, *current)
 				return &newForm, nil
 			}
 		case key.Matches(msg, m.keyMap.Delete):
 			current := m.Cursor()
+			if current != nil && current.Source != "" {
+				m.status = database.ErrReadOnly.Error()
+				return m, nil
+			}
+			if current != nil && !m.cfg.UI.ConfirmDelete {
+				return m.Update(DeleteConfirmMsg{current})
+			}
 			if current != nil {
-				dm := newConfirmModel(m, fmt.Sprintf("Are you sure you want to delete %s ?", current.Name))
+				dm := newConfirmModel(m, fmt.Sprintf("Are you sure you want to delete %s ?", current.Name), DeleteConfirmMsg{current})
 				return &dm, nil
 			}
+		case key.Matches(msg, m.keyMap.Keys):
+			km := newKeysModel(m, m.keys, m.Cursor())
+			return &km, km.Init()
+		case key.Matches(msg, m.keyMap.Identities):
+			im := newIdentitiesModel(m, m.identities)
+			return im, nil
+		case key.Matches(msg, m.keyMap.History):
+			current := m.Cursor()
+			if current != nil && current.Source != "" {
+				m.status = "shared connections have no history"
+				return m, nil
+			}
+			hm := newHistoryModel(m, m.history, current)
+			return hm, nil
+		case key.Matches(msg, m.keyMap.Trash):
+			tm := newTrashModel(m, m.trash)
+			return tm, nil
+		case key.Matches(msg, m.keyMap.Sort):
+			m.cycleSort()
+			return m, nil
+		case key.Matches(msg, m.keyMap.ReverseSort):
+			m.reverseSort()
+			return m, nil
+		case key.Matches(msg, m.keyMap.Columns):
+			cm := newColumnsModel(m)
+			return cm, nil
+		case key.Matches(msg, m.keyMap.Palette):
+			pm := newPaletteModel(m)
+			return pm, pm.Init()
+		case key.Matches(msg, m.keyMap.Details):
+			// 重新打开面板时重新探测主机
+			m.showDetails = !m.showDetails
+			clear(m.probes)
+			m.layout()
+			return m, nil
+		case key.Matches(msg, m.keyMap.Help, m.keyMap.FilterHelp):
+			m.showHelp = true
+			return m, nil
 		case key.Matches(msg, m.keyMap.Quit):
 			return m, tea.Quit
 		case key.Matches(msg, m.keyMap.Filter):
@@ -152,17 +298,50 @@ func (m *MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.updateTable()
 			return m, nil
 		}
+	case tea.MouseMsg:
+		return m.handleMouse(msg)
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.layout()
+		return m, nil
+	case probeMsg:
+		m.probes[msg.addr] = &msg.probe
+		if m.sortsBy("status") {
+			// 按状态排序时随探测结果重新排序, 光标停留在原来的连接上
+			if current := m.Cursor(); current != nil {
+				selected := *current
+				m.sortConns(m.currentItems)
+				m.setRows()
+				m.selectConn(selected)
+			}
+		} else if m.showsColumn("status") {
+			m.setRows()
+		}
+		return m, nil
+	case connDetailMsg:
+		m.details[msg.key] = &msg.detail
+		return m, nil
+	case storeTickMsg:
+		if err := m.checkStore(); err != nil {
+			m.status = err.Error()
+		}
+		return m, nil
 	case DeleteConfirmMsg:
 		if msg.context != nil {
-			err := m.db.DeleteConnection(msg.context.ID)
-			if err != nil {
-				panic(err)
+			if err := m.db.DeleteConnection(msg.context.ID); err != nil {
+				m.status = err.Error()
+				// 连接可能已被其他实例删除, 刷新列表以显示最新状态
+				m.reloadConnections()
+				return m, nil
 			}
-			m.connections, err = m.db.GetAllConnections()
-			if err != nil {
-				panic(err)
+			if err := m.reloadConnections(); err != nil {
+				m.status = err.Error()
+				return m, nil
+			}
+			if m.trash != nil {
+				m.setUndo(msg.context.ID)
+				m.status = fmt.Sprintf("Deleted %s, press %s to undo", msg.context.Name, m.keyMap.Undo.Help().Key)
 			}
-			m.updateTable()
 		}
 	}
 	var cmd tea.Cmd
@@ -173,9 +352,119 @@ func (m *MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, cmd
 	}
 
-	m.table, cmd = m.table.Update(msg)
-	return m, cmd
+	if msg, ok := msg.(tea.KeyMsg); ok && m.table.Focused() {
+		m.navigate(msg)
+	}
+	return m, nil
+}
+
+// navigate 处理表格的移动按键, 表格中只有显示的行, 由主界面移动光标和滚动
+func (m *MainModel) navigate(msg tea.KeyMsg) {
+	keys := m.table.KeyMap
+	row, height := m.cursorRow(), m.table.Height()
+	switch {
+	case key.Matches(msg, keys.LineUp):
+		row--
+	case key.Matches(msg, keys.LineDown):
+		row++
+	case key.Matches(msg, keys.PageUp):
+		row -= height
+	case key.Matches(msg, keys.PageDown):
+		row += height
+	case key.Matches(msg, keys.HalfPageUp):
+		row -= height / 2
+	case key.Matches(msg, keys.HalfPageDown):
+		row += height / 2
+	case key.Matches(msg, keys.GotoTop):
+		row = 0
+	case key.Matches(msg, keys.GotoBottom):
+		row = len(m.currentItems) - 1
+	default:
+		return
+	}
+	m.setCursor(row)
+}
+
+// cursorRow 返回光标所在连接在 currentItems 中的下标, 没有连接时为 -1
+func (m *MainModel) cursorRow() int {
+	if len(m.currentItems) == 0 {
+		return -1
+	}
+	return m.offset + m.table.Cursor()
+}
+
+// setCursor 将光标移到 currentItems 中的第 row 个连接, 光标移出显示范围时滚动表格
+func (m *MainModel) setCursor(row int) {
+	row = max(min(row, len(m.currentItems)-1), 0)
+	height := max(m.table.Height(), 1)
+	if row < m.offset {
+		m.offset = row
+	} else if row >= m.offset+height {
+		m.offset = row - height + 1
+	}
+	// 表格下方不留空行
+	m.offset = max(min(m.offset, len(m.currentItems)-height), 0)
+	m.setRows()
+	m.table.SetCursor(row - m.offset)
+}
+
+// newConn 返回使用配置中默认值的新连接
+func (m *MainModel) newConn() models.ConnInfo {
+	conn := models.ConnInfo{
+		Port:       m.cfg.Defaults.Port,
+		Username:   m.cfg.Defaults.Username,
+		AuthType:   models.UsePass,
+		PrivateKey: m.cfg.Defaults.PrivateKey,
+	}
+	if m.cfg.Defaults.Auth == "key" {
+		conn.AuthType = models.UseKey
+	}
+	return conn
+}
+
+// setUndo 设置可以撤销删除的连接, id 为 0 时不可撤销
+func (m *MainModel) setUndo(id int64) {
+	if m.keyMap.Undo.Enabled() != (id != 0) {
+		m.keyMap.Undo.SetEnabled(id != 0)
+		m.layout()
+	}
+	m.undoID = id
+}
+
+// undoDelete 将刚刚删除的连接移出回收站
+func (m *MainModel) undoDelete() {
+	id := m.undoID
+	m.setUndo(0)
+	if err := m.trash.RestoreConnection(id); err != nil {
+		m.status = err.Error()
+		return
+	}
+	if err := m.reloadConnections(); err != nil {
+		m.status = err.Error()
+	}
+}
+
+// connect 连接选中的主机, 主机配置了多个登录身份时先选择身份
+func (m *MainModel) connect(command models.RunCommand) (tea.Model, tea.Cmd) {
+	current := m.Cursor()
+	if current == nil {
+		return m, nil
+	}
+	if len(current.ExtraIdentityIDs) > 0 && m.identities != nil {
+		identities, err := m.identities.GetAllIdentities()
+		if err != nil {
+			m.status = err.Error()
+			return m, nil
+		}
+		cm := newChooserModel(m, *current, command, identities)
+		if len(cm.options) > 1 {
+			return cm, nil
+		}
+	}
+	m.WillConn = &models.RunContext{Context: current, Command: command}
+	return m, tea.Quit
 }
+
 func (m *MainModel) SwitchFocus(fi FocusView) {
 	switch fi {
 	case Table:
@@ -186,7 +475,18 @@ func (m *MainModel) SwitchFocus(fi FocusView) {
 		m.keyMap.Quit.SetEnabled(true)
 		m.keyMap.Connect.SetEnabled(true)
 		m.keyMap.SftpConnect.SetEnabled(true)
+		m.keyMap.Keys.SetEnabled(m.keys != nil)
+		m.keyMap.Identities.SetEnabled(m.identities != nil)
+		m.keyMap.History.SetEnabled(m.history != nil)
+		m.keyMap.Trash.SetEnabled(m.trash != nil)
+		m.keyMap.Details.SetEnabled(true)
+		m.keyMap.Sort.SetEnabled(true)
+		m.keyMap.ReverseSort.SetEnabled(true)
+		m.keyMap.Columns.SetEnabled(true)
+		m.keyMap.Palette.SetEnabled(true)
+		m.keyMap.Help.SetEnabled(true)
 		m.keyMap.FilterEnter.SetEnabled(false)
+		m.keyMap.FilterHelp.SetEnabled(false)
 		// m.keyMap.FilterCancel.SetEnabled(false)
 
 		m.filterInput.Blur()
@@ -199,28 +499,188 @@ func (m *MainModel) SwitchFocus(fi FocusView) {
 		m.keyMap.Quit.SetEnabled(false)
 		m.keyMap.Connect.SetEnabled(false)
 		m.keyMap.SftpConnect.SetEnabled(false)
+		m.keyMap.Keys.SetEnabled(false)
+		m.keyMap.Identities.SetEnabled(false)
+		m.keyMap.History.SetEnabled(false)
+		m.keyMap.Trash.SetEnabled(false)
+		m.keyMap.Details.SetEnabled(false)
+		m.keyMap.Sort.SetEnabled(false)
+		m.keyMap.ReverseSort.SetEnabled(false)
+		m.keyMap.Columns.SetEnabled(false)
+		m.keyMap.Palette.SetEnabled(false)
+		m.keyMap.Help.SetEnabled(false)
 		m.keyMap.FilterEnter.SetEnabled(true)
+		m.keyMap.FilterHelp.SetEnabled(true)
 		// m.keyMap.FilterCancel.SetEnabled(true)
 
 		m.filterInput.Focus()
 		m.table.Blur()
 	}
+	// 帮助的行数可能变化
+	m.layout()
 }
 
 func (m *MainModel) updateTable() {
-	rows := make([]table.Row, 0)
 	m.currentItems = make([]*models.ConnInfo, 0)
 	for _, conn := range m.connections {
-		if m.filter == "" ||
-			strings.Contains(strings.ToLower(conn.Name), strings.ToLower(m.filter)) ||
-			strings.Contains(strings.ToLower(conn.Host), strings.ToLower(m.filter)) ||
-			strings.Contains(strings.ToLower(conn.Username), strings.ToLower(m.filter)) {
-			rows = append(rows, genRow(&conn))
+		if conn.Matches(m.filter) {
 			m.currentItems = append(m.currentItems, &conn)
 		}
 	}
+	m.sortConns(m.currentItems)
+	m.offset = 0
+	m.setCursor(0)
+}
+
+// setRows 将过滤后的连接中当前显示的部分按显示的列填入表格
+func (m *MainModel) setRows() {
+	end := min(m.offset+max(m.table.Height(), 1), len(m.currentItems))
+	rows := make([]table.Row, 0, max(end-m.offset, 0))
+	for _, conn := range m.currentItems[min(m.offset, end):end] {
+		row := make(table.Row, len(m.visible))
+		for j, c := range m.visible {
+			row[j] = m.columns[c].value(m, conn)
+		}
+		rows = append(rows, row)
+	}
 	m.table.SetRows(rows)
-	m.table.SetCursor(0)
+}
+
+// setColumns 设置显示的列, names 为空时显示默认的列
+func (m *MainModel) setColumns(names []string) {
+	if len(names) == 0 {
+		names = defaultColumns
+	}
+	m.columns = m.columns[:0]
+	for _, name := range names {
+		c, ok := lookupColumn(name)
+		if !ok {
+			continue
+		}
+		if w := m.cfg.UI.ColumnWidths[name]; w > 0 {
+			c.Width = w
+		}
+		m.columns = append(m.columns, c)
+	}
+	m.layout()
+}
+
+// showsColumn 判断当前是否显示了该列
+func (m *MainModel) showsColumn(name string) bool {
+	for _, i := range m.visible {
+		if m.columns[i].name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// cycleSort 按显示的下一列排序, 最后一列之后恢复存储中的顺序
+// 只替换主要的排序列, 配置中的次要排序列保持不变
+func (m *MainModel) cycleSort() {
+	primary := ""
+	if len(m.sort) > 0 {
+		primary, _ = parseSort(m.sort[0])
+	}
+	names := make([]string, len(m.visible))
+	for j, i := range m.visible {
+		names[j] = m.columns[i].name
+	}
+	if len(names) == 0 {
+		return
+	}
+	// 没有排序列或排序列未显示时从第一列开始
+	next := names[0]
+	if pos := slices.Index(names, primary); pos >= 0 {
+		next = ""
+		if pos+1 < len(names) {
+			next = names[pos+1]
+		}
+	}
+	sort := slices.DeleteFunc(slices.Clone(m.sort), func(spec string) bool {
+		name, _ := parseSort(spec)
+		return name == primary || name == next
+	})
+	if next != "" {
+		sort = append([]string{next}, sort...)
+	}
+	m.setSort(sort)
+}
+
+// reverseSort 切换主要排序列的升降序
+func (m *MainModel) reverseSort() {
+	if len(m.sort) == 0 {
+		m.status = fmt.Sprintf("Press %s to choose a sort column first", m.keyMap.Sort.Help().Key)
+		return
+	}
+	sort := slices.Clone(m.sort)
+	name, desc := parseSort(sort[0])
+	sort[0] = sortSpec(name, !desc)
+	m.setSort(sort)
+}
+
+// setSort 重新排序并保持选中的连接, 排序在退出或保存列设置时才写入配置文件
+func (m *MainModel) setSort(sort []string) {
+	var selected models.ConnInfo
+	if current := m.Cursor(); current != nil {
+		selected = *current
+	}
+	m.sort = sort
+	m.updateTable()
+	m.selectConn(selected)
+	m.layout()
+	m.cfg.UI.Sort = sort
+	m.sortChanged = true
+}
+
+// saveSort 将 ui.sort 和 keys 指定的项写入配置文件
+func (m *MainModel) saveSort(keys ...string) error {
+	if err := m.cfg.Save(append(keys, "ui.sort")...); err != nil {
+		return err
+	}
+	m.sortChanged = false
+	return nil
+}
+
+// SaveSort 退出界面时将修改过的排序写入配置文件
+func (m *MainModel) SaveSort() error {
+	if !m.sortChanged {
+		return nil
+	}
+	return m.saveSort()
+}
+
+// layout 根据窗口大小调整列宽和表格高度
+func (m *MainModel) layout() {
+	tableWidth := m.width
+	if m.showDetails && m.sideDetails() {
+		tableWidth -= detailWidth
+	}
+	cols := make([]column, len(m.columns))
+	for i, c := range m.columns {
+		cols[i] = c.column
+		cols[i].Title += m.sortMark(c.name)
+	}
+	visible, columns := fitColumns(cols, tableWidth-tableBorder)
+	row := m.cursorRow()
+	// 先清空行, 避免列数减少时行的单元格多于列
+	m.table.SetRows(nil)
+	m.table.SetColumns(columns)
+	m.visible = visible
+
+	height := defaultTableHeight
+	if m.cfg.UI.TableHeight > 0 {
+		height = m.cfg.UI.TableHeight
+	} else if m.height > 0 {
+		// 状态栏总是预留一行, 避免出现提示时表格跳动; 帮助之后还有一个换行
+		other := []string{m.filterInput.View(), "", m.getHelpStr() + "\n"}
+		if m.showDetails && !m.sideDetails() {
+			other = append(other, strings.Repeat("\n", detailHeight+tableBorder-1))
+		}
+		height = tableHeight(m.height, other...)
+	}
+	m.table.SetHeight(height)
+	m.setCursor(row)
 }
 
 func (m *MainModel) View() string {
@@ -231,27 +691,52 @@ func (m *MainModel) View() string {
 		s.WriteString(filterBlurStyle.Render(m.filterInput.View()))
 	}
 	s.WriteString("\n")
-	s.WriteString(tableStyle.Render(m.table.View()))
-	help := helpStyle.Render(m.getHelpStr())
-	s.WriteString("\n" + help + "\n")
+	tableView := tableStyle.Render(m.table.View())
+	if m.showDetails {
+		if m.sideDetails() {
+			tableView = lipgloss.JoinHorizontal(lipgloss.Top, tableView, m.detailView(detailWidth, lipgloss.Height(tableView)))
+		} else {
+			tableView += "\n" + m.detailView(max(m.width, lipgloss.Width(tableView)), detailHeight+tableBorder)
+		}
+	}
+	if m.showHelp {
+		// 帮助窗口显示在表格的位置上, 过滤框和状态栏保持不动
+		tableView = lipgloss.Place(lipgloss.Width(tableView), lipgloss.Height(tableView), lipgloss.Center, lipgloss.Center, m.helpView())
+	}
+	s.WriteString(tableView)
+	s.WriteString("\n" + errorStyle.Render(truncate(m.status, m.width)))
+	s.WriteString("\n" + m.getHelpStr() + "\n")
 	return s.String()
 }
 
+// getHelpStr 底部一行的常用按键, 完整的按键在帮助窗口中
 func (m *MainModel) getHelpStr() string {
-	v := reflect.ValueOf(m.keyMap).Elem()
+	m.help.Width = m.width
+	return m.help.ShortHelpView(m.keyMap.ShortHelp())
+}
+
+// helpStr 列出按键映射结构体中所有启用的按键
+func helpStr(keyMap any) string {
 	b := strings.Builder{}
-	count := 0
+	for i, item := range helpItems(keyMap) {
+		b.WriteString(item)
+		if (i+1)%4 == 0 {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+func helpItems(keyMap any) []string {
+	v := reflect.ValueOf(keyMap).Elem()
+	var items []string
 	for i := 0; i < v.NumField(); i++ {
 		key := v.Field(i).Interface().(key.Binding)
 		if !key.Enabled() {
 			continue
 		}
-		count++
 		s := key.Help()
-		b.WriteString("'" + s.Key + "'-" + s.Desc + "  ")
-		if count%4 == 0 {
-			b.WriteString("\n")
-		}
+		items = append(items, "'"+s.Key+"'-"+s.Desc+"  ")
 	}
-	return b.String()
+	return items
 }
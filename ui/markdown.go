@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// 行内 Markdown 语法, 依次为代码、链接、粗体和斜体
+var (
+	mdCode   = regexp.MustCompile("`([^`]+)`")
+	mdLink   = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	mdBold   = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	mdItalic = regexp.MustCompile(`\*([^*\s][^*]*)\*|\b_([^_\s][^_]*)_\b`)
+	mdList   = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])\s+(.*)$`)
+)
+
+// renderMarkdown 将备注中常用的 Markdown 语法 (标题、列表、引用、代码块和行内样式)
+// 渲染为终端样式, 并按 width 折行, width 不大于 0 时不折行
+func renderMarkdown(text string, width int) string {
+	var lines []string
+	inCode := false
+	wrap := func(s string, indent int) {
+		style := lipgloss.NewStyle()
+		if width > indent {
+			style = style.Width(width - indent)
+		}
+		for _, l := range strings.Split(style.Render(s), "\n") {
+			lines = append(lines, strings.Repeat(" ", indent)+strings.TrimRight(l, " "))
+		}
+	}
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inCode = !inCode
+			continue
+		}
+		switch {
+		case inCode:
+			// 代码块保持原样, 只截断过长的行
+			lines = append(lines, noStyle.Render(truncate("  "+line, width)))
+		case strings.HasPrefix(trimmed, "#"):
+			title := strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+			wrap(titleStyle.Render(renderInline(title)), 0)
+		case strings.HasPrefix(trimmed, ">"):
+			quote := strings.TrimSpace(strings.TrimPrefix(trimmed, ">"))
+			for _, l := range strings.Split(lipgloss.NewStyle().Width(max(width-2, 1)).Render(renderInline(quote)), "\n") {
+				lines = append(lines, noStyle.Render("│ ")+strings.TrimRight(l, " "))
+			}
+		case mdList.MatchString(line):
+			m := mdList.FindStringSubmatch(line)
+			bullet := m[2]
+			if strings.ContainsAny(bullet, "-*+") {
+				bullet = "•"
+			}
+			indent := len(m[1]) + len(bullet) + 1
+			item := lipgloss.NewStyle().Width(max(width-indent, 1)).Render(renderInline(m[3]))
+			for i, l := range strings.Split(item, "\n") {
+				prefix := strings.Repeat(" ", indent)
+				if i == 0 {
+					prefix = m[1] + focusedStyle.Render(bullet) + " "
+				}
+				lines = append(lines, prefix+strings.TrimRight(l, " "))
+			}
+		default:
+			wrap(renderInline(line), 0)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderInline 渲染行内的代码、链接、粗体和斜体
+func renderInline(s string) string {
+	// 代码中的内容不再处理其他语法
+	var codes []string
+	s = mdCode.ReplaceAllStringFunc(s, func(m string) string {
+		codes = append(codes, m[1:len(m)-1])
+		return "\x00"
+	})
+	s = mdLink.ReplaceAllStringFunc(s, func(m string) string {
+		parts := mdLink.FindStringSubmatch(m)
+		return lipgloss.NewStyle().Underline(true).Render(parts[1]) + noStyle.Render(" ("+parts[2]+")")
+	})
+	s = mdBold.ReplaceAllStringFunc(s, func(m string) string {
+		return lipgloss.NewStyle().Bold(true).Render(m[2 : len(m)-2])
+	})
+	s = mdItalic.ReplaceAllStringFunc(s, func(m string) string {
+		return lipgloss.NewStyle().Italic(true).Render(m[1 : len(m)-1])
+	})
+	for _, code := range codes {
+		s = strings.Replace(s, "\x00", focusedStyle.Render(code), 1)
+	}
+	return s
+}
@@ -0,0 +1,190 @@
+package ui
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"time"
+	"tssh/models"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+)
+
+// 鼠标事件的坐标以终端左上角为原点, 启用鼠标时程序使用全屏模式, 各界面从第一行开始显示
+
+// doubleClickInterval 两次点击同一行的间隔小于该值时视为双击
+const doubleClickInterval = 500 * time.Millisecond
+
+// click 上一次点击的行和时间, 用于识别双击
+type click struct {
+	row int
+	at  time.Time
+}
+
+// leftClick 是否为按下鼠标左键
+func leftClick(msg tea.MouseMsg) bool {
+	return msg.Button == tea.MouseButtonLeft && msg.Action == tea.MouseActionPress
+}
+
+// handleMouse 点击选中连接, 双击连接, 滚轮移动光标, 点击过滤框开始过滤, 点击关闭帮助窗口
+func (m *MainModel) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	if m.showHelp {
+		// 与按键一样, 点击任意位置关闭帮助窗口
+		m.showHelp = !leftClick(msg)
+		return m, nil
+	}
+	switch {
+	case msg.Button == tea.MouseButtonWheelUp && msg.Action == tea.MouseActionPress:
+		m.setCursor(m.cursorRow() - 1)
+	case msg.Button == tea.MouseButtonWheelDown && msg.Action == tea.MouseActionPress:
+		m.setCursor(m.cursorRow() + 1)
+	case leftClick(msg):
+		if msg.Y < lipgloss.Height(filterBlurStyle.Render(m.filterInput.View())) {
+			m.SwitchFocus(FilterInput)
+			return m, nil
+		}
+		row := m.rowAt(msg.X, msg.Y)
+		if row < 0 {
+			return m, nil
+		}
+		if m.filterInput.Focused() {
+			m.SwitchFocus(Table)
+		}
+		m.setCursor(row)
+		if row == m.lastClick.row && time.Since(m.lastClick.at) < doubleClickInterval {
+			m.lastClick = click{}
+			return m.connect(models.RunCommandSsh)
+		}
+		m.lastClick = click{row: row, at: time.Now()}
+	}
+	return m, nil
+}
+
+// rowAt 返回屏幕上 (x, y) 处的连接在 currentItems 中的下标, 不在表格的行上时返回 -1
+func (m *MainModel) rowAt(x, y int) int {
+	if m.showDetails && m.sideDetails() && x >= m.width-detailWidth {
+		return -1
+	}
+	// 表格上方依次为过滤框、表格的上边框和表头, 表格从 offset 开始显示
+	header := lipgloss.Height(tableHeaderStyle.Render(""))
+	line := y - lipgloss.Height(filterBlurStyle.Render(m.filterInput.View())) - tableBorder/2 - header
+	if line < 0 || line >= m.table.Height() {
+		return -1
+	}
+	row := m.offset + line
+	if row >= len(m.currentItems) {
+		return -1
+	}
+	return row
+}
+
+// formSection 表单中依次显示的一部分, idx 为 -1 时不可获得焦点
+type formSection struct {
+	idx  int
+	view string
+}
+
+// fieldAt 返回表单第 y 行的元素, 点击按钮行时按 x 区分确认和取消按钮
+func (m formModel) fieldAt(x, y int) int {
+	line := 0
+	for _, s := range m.sections() {
+		if y >= line && y < line+lipgloss.Height(strings.TrimRight(s.view, "\n")) {
+			if s.idx == idxEnter && x >= lipgloss.Width(noStyle.Render(enterButton)) {
+				return idxCancel
+			}
+			return s.idx
+		}
+		line += strings.Count(s.view, "\n")
+	}
+	return -1
+}
+
+// click 点击表单: 聚焦点击的元素, 选择点击的选项, 按下点击的按钮
+func (m formModel) click(x, y int) (tea.Model, tea.Cmd) {
+	idx := m.fieldAt(x, y)
+	if idx < 0 || !slices.Contains(m.focusOrder(), idx) {
+		return m, nil
+	}
+	m.setFocus(idx)
+	switch idx {
+	case idxEnter, idxCancel:
+		return m.submit()
+	case idxAuthType:
+		switch optionAt(x, "> AuthType ", []string{"(x)Password", "(x)PrivateKey"}) {
+		case 0:
+			m.authTypeSelected = models.UsePass
+		case 1:
+			m.authTypeSelected = models.UseKey
+		}
+	case idxSecretBackend:
+		names := make([]string, len(models.SecretBackends))
+		for i, backend := range models.SecretBackends {
+			names[i] = "(x)" + secretBackendNames[backend]
+		}
+		if i := optionAt(x, "> Store ", names); i >= 0 {
+			m.secretBackend = i
+			m.updateSecretRefPlaceholder()
+		}
+	case idxIdentity:
+		names := []string{"(x)None"}
+		for _, identity := range m.identities {
+			names = append(names, "(x)"+identity.Name)
+		}
+		if i := optionAt(x, "> Identity ", names); i >= 0 {
+			m.identitySelected = i
+		}
+	case idxExtraIdentities:
+		names := make([]string, len(m.identities))
+		for i, identity := range m.identities {
+			names[i] = "[x]" + identity.Name
+		}
+		if i := optionAt(x, "> Also as ", names); i >= 0 {
+			m.extraCursor = i
+			id := m.identities[i].ID
+			m.extraSelected[id] = !m.extraSelected[id]
+		}
+	case idxImportKey:
+		if optionAt(x, "> Import ", []string{"[x]Store key in tssh"}) == 0 {
+			m.importKey = !m.importKey
+		}
+	}
+	return m, nil
+}
+
+// optionAt 返回 x 处的选项下标, 选项依次显示在标签之后, 以三个空格分隔
+func optionAt(x int, label string, options []string) int {
+	pos := runewidth.StringWidth(label)
+	for i, option := range options {
+		w := runewidth.StringWidth(option)
+		if x >= pos && x < pos+w {
+			return i
+		}
+		pos += w + 3
+	}
+	return -1
+}
+
+// buttonAt 返回对话框中 (x, y) 处的按钮, 不在按钮上时返回 -1
+// 按钮位于问题下方, 由对话框的边框、内边距和问题的大小推算位置, 与 View 的布局一致
+func (m confirmModel) buttonAt(x, y int) int {
+	yes, no := m.buttons()
+	question := questionStyle.Render(m.question)
+	top := dialogBoxStyle.GetBorderTopSize() + dialogBoxStyle.GetPaddingTop() + lipgloss.Height(question)
+	// 按钮的上边距不能点击
+	if y < top+buttonStyle.GetMarginTop() || y >= top+lipgloss.Height(yes) {
+		return -1
+	}
+	// 问题比按钮宽时按钮居中显示, 两个按钮之间隔一个空格
+	yesWidth, width := lipgloss.Width(yes), lipgloss.Width(yes)+1+lipgloss.Width(no)
+	left := dialogBoxStyle.GetBorderLeftSize() + dialogBoxStyle.GetPaddingLeft() +
+		int(math.Round(float64(max(lipgloss.Width(question)-width, 0))/2))
+	switch {
+	case x >= left && x < left+yesWidth:
+		return idxDialogYes
+	case x > left+yesWidth && x < left+width:
+		return idxDialogNo
+	}
+	return -1
+}
@@ -0,0 +1,178 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"tssh/config"
+	"tssh/database"
+	"tssh/models"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+func mouseModel(t *testing.T, n int) *MainModel {
+	t.Helper()
+	cfg := config.Default()
+	cfg.UI.Mouse = true
+	cfg.UI.ProbeHosts = false
+	connections := make([]models.ConnInfo, n)
+	for i := range connections {
+		connections[i] = models.ConnInfo{ID: int64(i + 1), Name: fmt.Sprintf("conn-%02d", i), Host: "10.0.0.1",
+			Port: 22, Username: "root", AuthType: models.UseKey}
+	}
+	m := InitialModel(connections, database.NewMemoryStore(connections...), cfg).(*MainModel)
+	m.Update(tea.WindowSizeMsg{Width: 100, Height: 20})
+	return m
+}
+
+func press(x, y int, button tea.MouseButton) tea.MouseMsg {
+	return tea.MouseMsg{X: x, Y: y, Button: button, Action: tea.MouseActionPress}
+}
+
+// screenRows 返回界面中每个连接名称所在的行
+func screenRows(view string) map[string]int {
+	rows := make(map[string]int)
+	for y, line := range strings.Split(ansi.Strip(view), "\n") {
+		if i := strings.Index(line, "conn-"); i >= 0 {
+			rows[line[i:i+len("conn-00")]] = y
+		}
+	}
+	return rows
+}
+
+func TestClickSelectsRow(t *testing.T) {
+	m := mouseModel(t, 50)
+	scrolls := []struct {
+		name string
+		keys []tea.KeyMsg
+	}{
+		{"top", nil},
+		{"page down", []tea.KeyMsg{{Type: tea.KeyPgDown}, {Type: tea.KeyPgDown}}},
+		{"bottom", []tea.KeyMsg{{Type: tea.KeyEnd}}},
+		{"back up", []tea.KeyMsg{{Type: tea.KeyUp}, {Type: tea.KeyPgUp}, {Type: tea.KeyUp}}},
+	}
+	for _, s := range scrolls {
+		for _, k := range s.keys {
+			m.Update(k)
+		}
+		rows := screenRows(m.View())
+		if len(rows) != m.table.Height() {
+			t.Fatalf("%s: %d rows on screen, table height %d", s.name, len(rows), m.table.Height())
+		}
+		for name, y := range rows {
+			m.lastClick = click{}
+			m.Update(press(5, y, tea.MouseButtonLeft))
+			if got := m.Cursor(); got == nil || got.Name != name {
+				t.Errorf("%s: click on line %d selected %v, want %s", s.name, y, got, name)
+			}
+			// 点击不会滚动表格
+			if after := screenRows(m.View()); after[name] != y {
+				t.Errorf("%s: %s moved from line %d to %d", s.name, name, y, after[name])
+			}
+		}
+	}
+}
+
+func TestClickOutsideRows(t *testing.T) {
+	m := mouseModel(t, 3)
+	rows := screenRows(m.View())
+	last := rows["conn-02"]
+	for _, y := range []int{1, 2, last + 1, 19} {
+		m.Update(press(5, 0, tea.MouseButtonLeft))
+		m.Update(press(5, rows["conn-01"], tea.MouseButtonLeft))
+		m.lastClick = click{}
+		m.Update(press(5, y, tea.MouseButtonLeft))
+		if got := m.Cursor(); got == nil || got.Name != "conn-01" {
+			t.Errorf("click on line %d changed the selection to %v", y, got)
+		}
+	}
+	// 点击第一行的过滤框开始过滤
+	m.Update(press(5, 0, tea.MouseButtonLeft))
+	if !m.filterInput.Focused() {
+		t.Error("click on the filter did not focus it")
+	}
+}
+
+func TestWheelScrolls(t *testing.T) {
+	m := mouseModel(t, 50)
+	for range 30 {
+		m.Update(press(5, 5, tea.MouseButtonWheelDown))
+	}
+	if got := m.Cursor(); got == nil || got.Name != "conn-30" {
+		t.Fatalf("wheel down selected %v, want conn-30", got)
+	}
+	if _, ok := screenRows(m.View())["conn-30"]; !ok {
+		t.Error("selected row scrolled out of view")
+	}
+	m.Update(press(5, 5, tea.MouseButtonWheelUp))
+	if got := m.Cursor(); got == nil || got.Name != "conn-29" {
+		t.Errorf("wheel up selected %v, want conn-29", got)
+	}
+}
+
+func TestDoubleClickConnects(t *testing.T) {
+	m := mouseModel(t, 5)
+	y := screenRows(m.View())["conn-03"]
+	m.Update(press(5, y, tea.MouseButtonLeft))
+	if m.WillConn != nil {
+		t.Fatal("single click connected")
+	}
+	if _, cmd := m.Update(press(5, y, tea.MouseButtonLeft)); cmd == nil || m.WillConn == nil || m.WillConn.Context.Name != "conn-03" {
+		t.Errorf("double click connected to %v", m.WillConn)
+	}
+}
+
+// buttonPos 返回按钮文字在对话框界面中的位置
+func buttonPos(t *testing.T, view, label string) (int, int) {
+	t.Helper()
+	lines := strings.Split(ansi.Strip(view), "\n")
+	for y := len(lines) - 1; y >= 0; y-- {
+		if x := strings.LastIndex(lines[y], label); x >= 0 && strings.Contains(lines[y], "Yes") && strings.Contains(lines[y], "No") {
+			return ansi.StringWidth(lines[y][:x]), y
+		}
+	}
+	t.Fatalf("no %s button in\n%s", label, view)
+	return 0, 0
+}
+
+func TestDialogButtons(t *testing.T) {
+	main := mouseModel(t, 1)
+	questions := []string{"Delete?", "Are you sure you want to delete a-connection-with-a-long-name ?"}
+	for _, question := range questions {
+		for _, focus := range []int{idxDialogYes, idxDialogNo} {
+			m := newConfirmModel(main, question, DeleteConfirmMsg{main.Cursor()})
+			m.focusIndex = focus
+			view := m.View()
+			yesX, yesY := buttonPos(t, view, "Yes")
+			noX, noY := buttonPos(t, view, "No")
+
+			if got := m.buttonAt(yesX, yesY); got != idxDialogYes {
+				t.Errorf("%q focus %d: buttonAt(Yes) = %d", question, focus, got)
+			}
+			if got := m.buttonAt(yesX+2, yesY); got != idxDialogYes {
+				t.Errorf("%q focus %d: buttonAt(Yes+2) = %d", question, focus, got)
+			}
+			if got := m.buttonAt(noX+1, noY); got != idxDialogNo {
+				t.Errorf("%q focus %d: buttonAt(No) = %d", question, focus, got)
+			}
+			for _, pos := range [][2]int{{yesX, yesY - 1}, {yesX, yesY + 1}, {0, yesY}, {noX + 20, noY}} {
+				if got := m.buttonAt(pos[0], pos[1]); got != -1 {
+					t.Errorf("%q focus %d: buttonAt(%d, %d) = %d, want -1", question, focus, pos[0], pos[1], got)
+				}
+			}
+
+			next, cmd := m.Update(press(yesX, yesY, tea.MouseButtonLeft))
+			if next != tea.Model(main) || cmd == nil {
+				t.Fatalf("clicking Yes returned %T, %v", next, cmd)
+			}
+			if msg, ok := cmd().(DeleteConfirmMsg); !ok || msg.context.Name != "conn-00" {
+				t.Errorf("clicking Yes sent %v", msg)
+			}
+			if next, cmd := m.Update(press(noX, noY, tea.MouseButtonLeft)); next != tea.Model(main) || cmd != nil {
+				t.Errorf("clicking No returned %T, %v", next, cmd)
+			}
+		}
+	}
+}
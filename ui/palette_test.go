@@ -0,0 +1,42 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestPaletteActions(t *testing.T) {
+	m := mouseModel(t, 3)
+	pm := newPaletteModel(m)
+	actions := make(map[string]bool)
+	for _, a := range pm.actions {
+		actions[a.action] = true
+	}
+	for _, want := range []string{"connect", "sftp_connect", "edit", "quit"} {
+		if !actions[want] {
+			t.Errorf("palette lacks %s", want)
+		}
+	}
+	for name := range actions {
+		if strings.HasPrefix(name, "filter_") || name == "palette" {
+			t.Errorf("palette lists %s", name)
+		}
+	}
+}
+
+func TestPaletteRunsAction(t *testing.T) {
+	m := mouseModel(t, 3)
+	pm := newPaletteModel(m)
+	for _, r := range "sftp" {
+		pm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	if len(pm.matches) == 0 || pm.matches[0].action != "sftp_connect" {
+		t.Fatalf("matches for sftp = %v", pm.matches)
+	}
+	next, cmd := pm.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if next != tea.Model(m) || cmd == nil || m.WillConn == nil || m.WillConn.Context.Name != "conn-00" {
+		t.Errorf("running sftp_connect returned %T, will connect %v", next, m.WillConn)
+	}
+}
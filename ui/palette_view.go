@@ -0,0 +1,199 @@
+package ui
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mattn/go-runewidth"
+)
+
+// paletteAction 命令面板中的一项, 对应主界面按键映射中的一个按键
+type paletteAction struct {
+	name    string // 帮助中的说明, 如 "connect sftp"
+	action  string // 配置中的按键名称, 如 "sftp_connect"
+	binding key.Binding
+}
+
+// paletteModel 按名称搜索并执行主界面的操作, 操作对选中的连接生效
+// 操作列表来自主界面的按键映射, 因此新增的按键和自定义的按键会自动出现在面板中
+type paletteModel struct {
+	actions   []paletteAction
+	matches   []paletteAction
+	cursor    int
+	input     textinput.Model
+	mainModel *MainModel
+	keyMap    *PaletteKeyMap
+}
+
+func newPaletteModel(mainModel *MainModel) *paletteModel {
+	m := &paletteModel{
+		mainModel: mainModel,
+		keyMap:    NewPaletteKeyMap(),
+	}
+	v := reflect.ValueOf(mainModel.keyMap).Elem()
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		b, ok := v.Field(i).Interface().(key.Binding)
+		if !ok || !b.Enabled() || len(b.Keys()) == 0 || t.Field(i).Name == "Palette" {
+			continue
+		}
+		// 带 scope 的按键只在过滤框等特定模式下有意义, 不作为命令列出
+		if t.Field(i).Tag.Get("scope") != "" {
+			continue
+		}
+		m.actions = append(m.actions, paletteAction{
+			name:    b.Help().Desc,
+			action:  snakeCase(t.Field(i).Name),
+			binding: b,
+		})
+	}
+	m.input = textinput.New()
+	m.input.Prompt = "> "
+	m.input.Placeholder = "Type to search commands"
+	m.input.Width = 30
+	m.input.Focus()
+	m.filter()
+	return m
+}
+
+func (m *paletteModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m *paletteModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		// 主界面也需要知道窗口大小
+		m.mainModel.Update(msg)
+		return m, nil
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keyMap.Back):
+			return m.mainModel, nil
+		case key.Matches(msg, m.keyMap.Up):
+			m.cursor = max(m.cursor-1, 0)
+			return m, nil
+		case key.Matches(msg, m.keyMap.Down):
+			m.cursor = max(min(m.cursor+1, len(m.matches)-1), 0)
+			return m, nil
+		case key.Matches(msg, m.keyMap.Run):
+			if len(m.matches) == 0 {
+				return m, nil
+			}
+			return m.run(m.matches[m.cursor])
+		}
+	}
+	var cmd tea.Cmd
+	query := m.input.Value()
+	m.input, cmd = m.input.Update(msg)
+	if m.input.Value() != query {
+		m.filter()
+	}
+	return m, cmd
+}
+
+// run 关闭面板, 在主界面按下该操作绑定的第一个键
+func (m *paletteModel) run(a paletteAction) (tea.Model, tea.Cmd) {
+	k := a.binding.Keys()[0]
+	msg, ok := keyPress(k)
+	if !ok {
+		m.mainModel.status = fmt.Sprintf("Cannot run %s: unsupported key %q", a.name, k)
+		return m.mainModel, nil
+	}
+	return m.mainModel.Update(msg)
+}
+
+// filter 按输入的内容模糊匹配操作的说明和名称, 匹配度高的在前
+func (m *paletteModel) filter() {
+	query := strings.ToLower(strings.TrimSpace(m.input.Value()))
+	type scored struct {
+		action paletteAction
+		score  int
+	}
+	var found []scored
+	for _, a := range m.actions {
+		score, ok := fuzzyMatch(query, a.name)
+		if s, ok2 := fuzzyMatch(query, a.action); ok2 && (!ok || s > score) {
+			score, ok = s, true
+		}
+		if ok {
+			found = append(found, scored{a, score})
+		}
+	}
+	slices.SortStableFunc(found, func(a, b scored) int { return b.score - a.score })
+	m.matches = m.matches[:0]
+	for _, f := range found {
+		m.matches = append(m.matches, f.action)
+	}
+	m.cursor = 0
+}
+
+// fuzzyMatch query 的字符是否依次出现在 text 中 (忽略大小写)
+// 分数奖励连续匹配和在单词开头匹配, 空的 query 匹配所有内容
+func fuzzyMatch(query, text string) (int, bool) {
+	text = strings.ToLower(text)
+	score := 0
+	qi := 0
+	q := []rune(query)
+	prevMatched := false
+	prev := ' '
+	for _, r := range text {
+		if qi < len(q) && r == q[qi] {
+			score++
+			if prevMatched {
+				score += 2
+			}
+			if !unicode.IsLetter(prev) && !unicode.IsDigit(prev) {
+				score += 3
+			}
+			qi++
+			prevMatched = true
+		} else {
+			prevMatched = false
+		}
+		prev = r
+	}
+	return score, qi == len(q)
+}
+
+func (m *paletteModel) View() string {
+	var b strings.Builder
+	title := "Commands"
+	if conn := m.mainModel.Cursor(); conn != nil {
+		title += " for " + conn.Name
+	}
+	b.WriteString(titleStyle.Render(title) + "\n\n")
+	b.WriteString(m.input.View() + "\n\n")
+
+	// 窗口较矮时只显示光标附近的操作
+	height := len(m.matches)
+	if m.mainModel.height > 0 {
+		height = max(m.mainModel.height-8, 3)
+	}
+	start := max(min(m.cursor-height/2, len(m.matches)-height), 0)
+	end := min(start+height, len(m.matches))
+	nameWidth := 0
+	for _, a := range m.matches {
+		nameWidth = max(nameWidth, runewidth.StringWidth(a.name))
+	}
+	for i := start; i < end; i++ {
+		a := m.matches[i]
+		line := runewidth.FillRight(a.name, nameWidth+2) + a.binding.Help().Key
+		if i == m.cursor {
+			b.WriteString(focusedStyle.Render("> "+line) + "\n")
+		} else {
+			b.WriteString("  " + line + "\n")
+		}
+	}
+	if len(m.matches) == 0 {
+		b.WriteString(noStyle.Render("  No matching commands") + "\n")
+	}
+	b.WriteString("\n" + helpStyle.Render(helpStr(m.keyMap)) + "\n")
+	return b.String()
+}
@@ -0,0 +1,63 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"tssh/config"
+	"tssh/database"
+	"tssh/models"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestSortSavedOnlyOnQuit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	const initial = "# my settings\n"
+	if err := os.WriteFile(path, []byte(initial), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	connections := []models.ConnInfo{
+		{ID: 1, Name: "b", Host: "b.example.com", Port: 22, Username: "root", AuthType: models.UseKey},
+		{ID: 2, Name: "a", Host: "a.example.com", Port: 22, Username: "root", AuthType: models.UseKey},
+	}
+	m := InitialModel(connections, database.NewMemoryStore(connections...), cfg).(*MainModel)
+	m.Update(tea.WindowSizeMsg{Width: 120, Height: 30})
+
+	for _, k := range []string{"s", "s", "S"} {
+		m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+	}
+	if len(m.sort) == 0 {
+		t.Fatal("s did not sort the list")
+	}
+	if data, _ := os.ReadFile(path); string(data) != initial {
+		t.Fatalf("sorting wrote the config file before quitting:\n%s", data)
+	}
+
+	if err := m.SaveSort(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "sort:") || !strings.Contains(string(data), m.sort[0]) {
+		t.Errorf("config does not contain the sort %v:\n%s", m.sort, data)
+	}
+
+	// 没有修改时退出不再写入
+	if err := os.WriteFile(path, []byte(initial), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SaveSort(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != initial {
+		t.Errorf("unchanged sort rewrote the config file:\n%s", data)
+	}
+}
@@ -1,37 +1,99 @@
 package ui
 
 import (
+	"github.com/charmbracelet/bubbles/table"
 	"github.com/charmbracelet/lipgloss"
 )
 
+// 界面使用的样式, 由 applyTheme 根据主题生成
 var (
-	tableStyle   = lipgloss.NewStyle().Border(lipgloss.NormalBorder())
-	footStyle    = lipgloss.NewStyle().Margin(0, 1)
-	focusedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
-	noStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("243"))
-	titleStyle   = lipgloss.NewStyle().Bold(true)
-	errorStyle   = lipgloss.NewStyle().MaxWidth(80).Inline(true).Foreground(lipgloss.Color("196"))
+	tableStyle       lipgloss.Style
+	footStyle        lipgloss.Style
+	focusedStyle     lipgloss.Style
+	noStyle          lipgloss.Style
+	titleStyle       lipgloss.Style
+	errorStyle       lipgloss.Style
+	filterFocusStyle lipgloss.Style
+	filterBlurStyle  lipgloss.Style
+	detailStyle      lipgloss.Style
+
+	dialogBoxStyle     lipgloss.Style
+	buttonStyle        lipgloss.Style
+	focusedButtonStyle lipgloss.Style
+	questionStyle      lipgloss.Style
+	helpStyle          lipgloss.Style
+	helpKeyStyle       lipgloss.Style
+	placeholderStyle   lipgloss.Style
+
+	tableHeaderStyle   lipgloss.Style
+	tableSelectedStyle lipgloss.Style
+)
+
+func init() {
+	applyTheme(builtinThemes["dark"])
+}
+
+// applyTheme 根据主题生成所有样式
+// 主题没有设置选中行或按钮的背景色时 (例如 NO_COLOR), 使用反色显示
+func applyTheme(t Theme) {
+	tableStyle = lipgloss.NewStyle().Border(lipgloss.NormalBorder())
+	footStyle = lipgloss.NewStyle().Margin(0, 1)
+	focusedStyle = lipgloss.NewStyle().Foreground(color(t.Accent)).Bold(t.Accent == "")
+	noStyle = lipgloss.NewStyle().Foreground(color(t.Muted))
+	titleStyle = lipgloss.NewStyle().Bold(true)
+	errorStyle = lipgloss.NewStyle().MaxWidth(80).Inline(true).Foreground(color(t.Error))
+	filterFocusStyle = footStyle.Foreground(color(t.Filter))
+	filterBlurStyle = footStyle.Foreground(color(t.Help))
+	detailStyle = tableStyle.Padding(0, 1)
 
 	dialogBoxStyle = lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color("63")).
-			Padding(1, 2).
-			BorderTop(true).
-			BorderLeft(true).
-			BorderRight(true).
-			BorderBottom(true)
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(color(t.Dialog)).
+		Padding(1, 2).
+		BorderTop(true).
+		BorderLeft(true).
+		BorderRight(true).
+		BorderBottom(true)
 
 	buttonStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("231")).
-			Background(lipgloss.Color("57")).
-			Padding(0, 3).
-			MarginTop(1)
+		Foreground(color(t.ButtonFg)).
+		Background(color(t.ButtonBg)).
+		Padding(0, 3).
+		MarginTop(1)
 
 	focusedButtonStyle = buttonStyle.
-				Foreground(lipgloss.Color("231")).
-				Background(lipgloss.Color("99"))
+		Background(color(t.FocusedButtonBg)).
+		Reverse(t.FocusedButtonBg == "")
 
 	questionStyle = lipgloss.NewStyle().Bold(true).MarginBottom(1)
 
-	helpStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
-)
+	helpStyle = lipgloss.NewStyle().Foreground(color(t.Help))
+	helpKeyStyle = helpStyle.Bold(true)
+	placeholderStyle = lipgloss.NewStyle().Foreground(color(t.Help))
+
+	tableHeaderStyle = lipgloss.NewStyle().
+		Padding(0, 1).
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(color(t.Border)).
+		BorderBottom(true)
+	tableSelectedStyle = lipgloss.NewStyle().
+		Foreground(color(t.SelectedFg)).
+		Background(color(t.SelectedBg)).
+		Reverse(t.SelectedBg == "")
+}
+
+// tableStyles 返回所有表格使用的样式
+func tableStyles() table.Styles {
+	s := table.DefaultStyles()
+	s.Header = tableHeaderStyle
+	s.Selected = tableSelectedStyle
+	return s
+}
+
+// color 空字符串表示不设置颜色
+func color(c string) lipgloss.TerminalColor {
+	if c == "" {
+		return lipgloss.NoColor{}
+	}
+	return lipgloss.Color(c)
+}
@@ -0,0 +1,169 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Theme 界面配色, 颜色为 ANSI 编号 ("205") 或十六进制 ("#268bd2"), 为空表示使用终端默认颜色
+type Theme struct {
+	// Base 自定义主题继承的内置主题, 未设置的颜色沿用该主题
+	Base            string `yaml:"base,omitempty"`
+	Accent          string `yaml:"accent"`
+	Muted           string `yaml:"muted"`
+	Error           string `yaml:"error"`
+	Help            string `yaml:"help"`
+	Filter          string `yaml:"filter"`
+	Border          string `yaml:"border"`
+	Dialog          string `yaml:"dialog"`
+	SelectedFg      string `yaml:"selected_fg"`
+	SelectedBg      string `yaml:"selected_bg"`
+	ButtonFg        string `yaml:"button_fg"`
+	ButtonBg        string `yaml:"button_bg"`
+	FocusedButtonBg string `yaml:"focused_button_bg"`
+}
+
+// 特殊的主题名称
+const (
+	// ThemeAuto 根据终端背景色选择 dark 或 light
+	ThemeAuto = "auto"
+	// ThemeNone 不使用颜色, 设置了 NO_COLOR 环境变量时总是使用
+	ThemeNone = "none"
+)
+
+var builtinThemes = map[string]Theme{
+	"dark": {
+		Accent:          "205",
+		Muted:           "243",
+		Error:           "196",
+		Help:            "240",
+		Filter:          "229",
+		Border:          "240",
+		Dialog:          "63",
+		SelectedFg:      "229",
+		SelectedBg:      "57",
+		ButtonFg:        "231",
+		ButtonBg:        "57",
+		FocusedButtonBg: "99",
+	},
+	"light": {
+		Accent:          "161",
+		Muted:           "240",
+		Error:           "160",
+		Help:            "244",
+		Filter:          "25",
+		Border:          "250",
+		Dialog:          "62",
+		SelectedFg:      "231",
+		SelectedBg:      "62",
+		ButtonFg:        "231",
+		ButtonBg:        "62",
+		FocusedButtonBg: "98",
+	},
+	"high-contrast": {
+		Accent:          "11",
+		Muted:           "15",
+		Error:           "9",
+		Help:            "15",
+		Filter:          "14",
+		Border:          "15",
+		Dialog:          "15",
+		SelectedFg:      "0",
+		SelectedBg:      "11",
+		ButtonFg:        "0",
+		ButtonBg:        "15",
+		FocusedButtonBg: "11",
+	},
+	"solarized": {
+		Accent:          "#d33682",
+		Muted:           "#839496",
+		Error:           "#dc322f",
+		Help:            "#93a1a1",
+		Filter:          "#b58900",
+		Border:          "#586e75",
+		Dialog:          "#268bd2",
+		SelectedFg:      "#fdf6e3",
+		SelectedBg:      "#268bd2",
+		ButtonFg:        "#fdf6e3",
+		ButtonBg:        "#586e75",
+		FocusedButtonBg: "#268bd2",
+	},
+	ThemeNone: {},
+}
+
+// ThemeNames 返回内置主题的名称
+func ThemeNames() []string {
+	names := make([]string, 0, len(builtinThemes)+1)
+	for name := range builtinThemes {
+		names = append(names, name)
+	}
+	names = append(names, ThemeAuto)
+	slices.Sort(names)
+	return names
+}
+
+// SetTheme 选择界面主题, name 为内置主题名称、themeDir 中的 <name>.yaml 或主题文件的路径
+func SetTheme(name string, themeDir string) error {
+	if os.Getenv("NO_COLOR") != "" {
+		// NO_COLOR 只禁止颜色, 仍然需要反色和粗体标示选中的行和按钮
+		name = ThemeNone
+		lipgloss.SetColorProfile(termenv.ANSI)
+	}
+	t, err := loadTheme(name, themeDir)
+	if err != nil {
+		return err
+	}
+	applyTheme(t)
+	return nil
+}
+
+func loadTheme(name string, themeDir string) (Theme, error) {
+	if name == "" || name == ThemeAuto {
+		name = "light"
+		if lipgloss.HasDarkBackground() {
+			name = "dark"
+		}
+	}
+	if t, ok := builtinThemes[name]; ok {
+		return t, nil
+	}
+
+	path := name
+	if !strings.ContainsRune(name, filepath.Separator) && filepath.Ext(name) == "" {
+		path = filepath.Join(themeDir, name+".yaml")
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Theme{}, fmt.Errorf("unknown theme %q, available: %s", name, strings.Join(ThemeNames(), ", "))
+	}
+	if err != nil {
+		return Theme{}, err
+	}
+
+	// 先读取继承的主题, 再用文件中的颜色覆盖
+	var header Theme
+	if err := yaml.Unmarshal(data, &header); err != nil {
+		return Theme{}, fmt.Errorf("%s: %w", path, err)
+	}
+	var t Theme
+	if header.Base != "" {
+		base, ok := builtinThemes[header.Base]
+		if !ok {
+			return Theme{}, fmt.Errorf("%s: unknown base theme %q", path, header.Base)
+		}
+		t = base
+	}
+	if err := yaml.Unmarshal(data, &t); err != nil {
+		return Theme{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return t, nil
+}
@@ -0,0 +1,163 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"tssh/database"
+	"tssh/models"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// trashModel 显示回收站中的连接, 可恢复或彻底删除
+type trashModel struct {
+	table     table.Model
+	conns     []models.TrashedConn
+	mainModel tea.Model
+	store     database.TrashStore
+	keyMap    *TrashKeyMap
+	purgeID   int64 // 再次按下删除键时彻底删除的连接
+	status    string
+	err       error
+}
+
+var trashColumns = []column{
+	{Title: "Deleted", Width: 16},
+	{Title: "Name", Width: 15, Flex: 2},
+	{Title: "Host", Width: 15, Flex: 3},
+	{Title: "Port", Width: 6},
+	{Title: "Username", Width: 10, Flex: 1},
+}
+
+func newTrashModel(mainModel tea.Model, store database.TrashStore) *trashModel {
+	_, columns := fitColumns(trashColumns, 0)
+	t := table.New(
+		table.WithColumns(columns),
+		table.WithFocused(true),
+		table.WithHeight(defaultTableHeight),
+	)
+	t.SetStyles(tableStyles())
+
+	m := &trashModel{
+		table:     t,
+		mainModel: mainModel,
+		store:     store,
+		keyMap:    NewTrashKeyMap(),
+	}
+	m.reload()
+	main := mainModel.(*MainModel)
+	m.resize(main.width, main.height)
+	return m
+}
+
+func (m *trashModel) reload() {
+	conns, err := m.store.TrashedConnections()
+	if err != nil {
+		m.err = err
+		return
+	}
+	m.conns = conns
+	rows := make([]table.Row, 0, len(conns))
+	for _, conn := range conns {
+		rows = append(rows, table.Row{
+			conn.DeletedAt.Local().Format("2006-01-02 15:04"),
+			conn.Name,
+			conn.Host,
+			fmt.Sprint(conn.Port),
+			conn.Username,
+		})
+	}
+	m.table.SetRows(rows)
+	if m.table.Cursor() >= len(rows) {
+		m.table.SetCursor(0)
+	}
+}
+
+func (m *trashModel) cursor() *models.TrashedConn {
+	idx := m.table.Cursor()
+	if idx < 0 || idx >= len(m.conns) {
+		return nil
+	}
+	return &m.conns[idx]
+}
+
+// resize 根据窗口大小调整表格
+func (m *trashModel) resize(width, height int) {
+	resizeTable(&m.table, trashColumns, width, height, "Trash", "", helpStyle.Render(helpStr(m.keyMap))+"\n")
+}
+
+func (m *trashModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *trashModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.WindowSizeMsg); ok {
+		// 主界面也需要知道窗口大小
+		m.mainModel.Update(msg)
+		m.resize(msg.Width, msg.Height)
+		return m, nil
+	}
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		purgeID := m.purgeID
+		m.purgeID = 0
+		switch {
+		case key.Matches(msg, m.keyMap.Back):
+			return m.mainModel, nil
+		case key.Matches(msg, m.keyMap.Restore):
+			conn := m.cursor()
+			if conn == nil {
+				return m, nil
+			}
+			if err := m.store.RestoreConnection(conn.ID); err != nil {
+				m.err = err
+				return m, nil
+			}
+			m.err = nil
+			m.status = fmt.Sprintf("Restored %s", conn.Name)
+			m.reload()
+			if err := m.mainModel.(*MainModel).reloadConnections(); err != nil {
+				m.err = err
+			}
+			return m, nil
+		case key.Matches(msg, m.keyMap.Purge):
+			conn := m.cursor()
+			if conn == nil {
+				return m, nil
+			}
+			// 彻底删除无法撤销, 需要连续按两次删除键
+			if purgeID != conn.ID {
+				m.purgeID = conn.ID
+				m.err = nil
+				m.status = fmt.Sprintf("Press %s again to delete %s forever", m.keyMap.Purge.Help().Key, conn.Name)
+				return m, nil
+			}
+			if err := m.store.PurgeConnection(conn.ID); err != nil {
+				m.err = err
+				return m, nil
+			}
+			m.err = nil
+			m.status = fmt.Sprintf("Deleted %s forever", conn.Name)
+			m.reload()
+			return m, nil
+		}
+		m.status = ""
+	}
+	var cmd tea.Cmd
+	m.table, cmd = m.table.Update(msg)
+	return m, cmd
+}
+
+func (m *trashModel) View() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("Trash") + "\n")
+	b.WriteString(tableStyle.Render(m.table.View()) + "\n")
+	if m.err != nil {
+		b.WriteString(errorStyle.Render(m.err.Error()) + "\n")
+	} else {
+		b.WriteString(noStyle.Render(m.status) + "\n")
+	}
+	b.WriteString(helpStyle.Render(helpStr(m.keyMap)) + "\n")
+	return b.String()
+}